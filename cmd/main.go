@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bukhavtsov/artems-dictionary/db"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
 	"github.com/bukhavtsov/artems-dictionary/internal/infrastructure"
 	middlewareInternal "github.com/bukhavtsov/artems-dictionary/internal/middleware"
 	"github.com/bukhavtsov/artems-dictionary/internal/server"
@@ -19,6 +22,12 @@ import (
 )
 
 var (
+	llmProvider = os.Getenv("LLM_PROVIDER")
+	llmAPIURL   = os.Getenv("LLM_API_URL")
+	llmAPIKey   = os.Getenv("LLM_API_KEY")
+	llmModel    = os.Getenv("LLM_MODEL")
+
+	// deprecated, kept as a fallback for LLM_API_URL and LLM_API_KEY
 	chatGPTAPIURL = os.Getenv("CHAT_GPT_API_URL")
 	apiKey        = os.Getenv("OPEN_AI_API_KEY")
 
@@ -39,7 +48,14 @@ var (
 	tlsKeyFile    = os.Getenv("TLS_KEY_FILE")
 	disableTLSEnv = os.Getenv("DISABLE_TLS")
 
+	autoMigrateEnv = os.Getenv("AUTO_MIGRATE")
+
+	ttsAPIURL = os.Getenv("TTS_API_URL")
 	ttsAPIKey = os.Getenv("TTS_API_KEY")
+
+	mochiAPIURL = os.Getenv("MOCHI_API_URL")
+
+	ankiConnectAllowedAddresses = os.Getenv("ANKICONNECT_ALLOWED_ADDRESSES")
 )
 
 func main() {
@@ -51,6 +67,38 @@ func main() {
 		logger.Error("Unable to connect to the database", slog.Any("err", err))
 		return
 	}
+	migrator, err := infrastructure.NewMigrator(conn, db.Migrations, "migrations")
+	if err != nil {
+		logger.Error("Unable to load migrations", slog.Any("err", err))
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			logger.Error("Migration has failed", slog.Any("err", err))
+			os.Exit(1)
+		}
+		return
+	}
+	var autoMigrate bool
+	if autoMigrateEnv != "" {
+		autoMigrate, err = strconv.ParseBool(autoMigrateEnv)
+		if err != nil {
+			logger.Error("Unable to parse AUTO_MIGRATE", slog.Any("err", err))
+			return
+		}
+	}
+	if autoMigrate {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			logger.Error("Unable to migrate the database", slog.Any("err", err))
+			return
+		}
+		logger.Info("Database is migrated", slog.Int("applied", len(applied)), slog.Int("version", migrator.LatestVersion()))
+	}
+	if err := migrator.CheckVersion(context.Background()); err != nil {
+		logger.Error("Database schema check has failed", slog.Any("err", err))
+		return
+	}
 
 	jwtRefreshTokenExpTimeDuration, err := time.ParseDuration(jwtRefreshTokenExpTime)
 	if err != nil {
@@ -75,16 +123,39 @@ func main() {
 	)
 	authService := usecase.NewAuthService(*authRepository, *jwtAuth)
 	translationRepository := infrastructure.NewTranslationRepository(conn)
+	if llmAPIURL == "" {
+		llmAPIURL = chatGPTAPIURL
+	}
+	if llmAPIKey == "" {
+		llmAPIKey = apiKey
+	}
+	httpClient := infrastructure.NewHTTPClient(infrastructure.DefaultHTTPClientConfig())
+	llmClient, err := newLLMClient(httpClient, llmProvider, llmAPIURL, llmAPIKey, llmModel)
+	if err != nil {
+		logger.Error("Unable to configure LLM provider", slog.Any("err", err))
+		return
+	}
+	translator := usecase.NewLLMTranslator(llmClient)
+	allowedAddresses, err := infrastructure.ParseAllowedAddresses(ankiConnectAllowedAddresses)
+	if err != nil {
+		logger.Error("Unable to parse ANKICONNECT_ALLOWED_ADDRESSES", slog.Any("err", err))
+		return
+	}
+	// the AnkiConnect URLs are given by the users, they must not reach the private network of the server
+	ankiConnectConfig := infrastructure.DefaultHTTPClientConfig()
+	ankiConnectConfig.Transport = infrastructure.NewPublicTransport(allowedAddresses)
 	translatorServer := server.NewTranslatorServer(
 		*authService,
 		*jwtAuth,
 		jwtAccessTokenExpTimeDuration,
 		jwtRefreshTokenExpTimeDuration,
 		translationRepository,
+		translationRepository,
 		*logger,
-		chatGPTAPIURL,
-		apiKey,
-		ttsAPIKey,
+		translator,
+		infrastructure.NewPaplaTTSClient(httpClient, ttsAPIURL, ttsAPIKey),
+		usecase.NewMochiSync(translationRepository, infrastructure.NewMochiClient(httpClient, mochiAPIURL)),
+		usecase.NewAnkiSync(translationRepository, infrastructure.NewAnkiConnectClient(infrastructure.NewHTTPClient(ankiConnectConfig))),
 	)
 
 	apiGroup := e.Group("/api")
@@ -100,13 +171,38 @@ func main() {
 	apiGroup.POST("/collections", translatorServer.CreateCollection)
 	apiGroup.POST("/tts", translatorServer.TextToSpeech)
 	apiGroup.DELETE("/collections/:collectionID", translatorServer.DeleteCollection)
+	apiGroup.PATCH("/collections/:collectionID", translatorServer.UpdateCollection)
+	apiGroup.PATCH("/collections/:collectionID/review-settings", translatorServer.UpdateReviewSettings)
 	apiGroup.GET("/collections/:collectionID/translations", translatorServer.GetCollectionsTranslations)
 	apiGroup.DELETE("/collections/:collectionID/translations", translatorServer.DeleteCollectionsTranslations)
 	apiGroup.GET("/collections/:collectionID/export", translatorServer.ExportCollectionsTranslations)
+	apiGroup.POST("/collections/:collectionID/import", translatorServer.ImportCollectionTranslations)
+	apiGroup.PUT("/collections/:collectionID/sync/mochi", translatorServer.SetCollectionMochiDeck)
+	apiGroup.POST("/collections/:collectionID/sync/mochi", translatorServer.SyncCollectionToMochi)
+	apiGroup.POST("/collections/:collectionID/sync/anki", translatorServer.SyncCollectionWithAnki)
+	apiGroup.GET("/collections/:collectionID/quiz", translatorServer.GetQuiz)
+	apiGroup.POST("/collections/:collectionID/quiz/answers", translatorServer.AnswerQuiz)
+	apiGroup.GET("/collections/:collectionID/translations/:collectionTranslationID/history", translatorServer.GetReviewHistory)
+	apiGroup.POST("/collections/:collectionID/translations/:collectionTranslationID/suspend", translatorServer.SuspendCollectionTranslation)
+	apiGroup.POST("/collections/:collectionID/translations/:collectionTranslationID/unsuspend", translatorServer.UnsuspendCollectionTranslation)
+	apiGroup.POST("/collections/:collectionID/translations/:collectionTranslationID/bury", translatorServer.BuryCollectionTranslation)
 	apiGroup.DELETE("/accounts", translatorServer.DeleteUsersAccount)
+	apiGroup.GET("/accounts/settings", translatorServer.GetAccountSettings)
+	apiGroup.PATCH("/accounts/settings", translatorServer.UpdateAccountSettings)
+	apiGroup.PUT("/accounts/mochi", translatorServer.ConnectMochi)
+	apiGroup.DELETE("/accounts/mochi", translatorServer.DisconnectMochi)
+	apiGroup.PUT("/accounts/ankiconnect", translatorServer.ConnectAnkiConnect)
+	apiGroup.DELETE("/accounts/ankiconnect", translatorServer.DisconnectAnkiConnect)
+	apiGroup.GET("/search", translatorServer.SearchTranslations)
+	apiGroup.GET("/stats", translatorServer.GetStats)
+	apiGroup.GET("/collections/:collectionID/stats", translatorServer.GetCollectionStats)
 
     apiGroup.GET("/review", translatorServer.GetDueCollectionTranslation)
 	apiGroup.POST("/review/:collection_id/:id", translatorServer.RateCollectionTranslation)
+	apiGroup.POST("/review/:collection_id/:id/answer", translatorServer.AnswerCollectionTranslation)
+	apiGroup.POST("/review/sessions", translatorServer.CreateReviewSession)
+	apiGroup.GET("/review/sessions/:sessionID", translatorServer.GetReviewSession)
+	apiGroup.POST("/review/sessions/:sessionID/answers", translatorServer.AnswerReviewSession)
 
 	authGroup := e.Group("/auth")
 	authGroup.Use(
@@ -133,3 +229,64 @@ func main() {
 		slog.Error("server has failed", slog.Any("err", e.StartTLS(":8080", tlsCertFile, tlsKeyFile)))
 	}
 }
+
+// migrate runs the `migrate up|down [steps]|status` subcommand
+func migrate(ctx context.Context, migrator *infrastructure.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]|status")
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("schema is at version %d\n", migrator.LatestVersion())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+	return nil
+}
+
+// newLLMClient creates the client of the configured LLM provider, OpenAI is the default
+func newLLMClient(httpClient *infrastructure.HTTPClient, provider, apiURL, apiKey, model string) (usecase.LLMClient, error) {
+	switch provider {
+	case domain.LLMProviderOpenAI, "":
+		return infrastructure.NewOpenAIClient(httpClient, apiURL, apiKey, model), nil
+	case domain.LLMProviderAnthropic:
+		return infrastructure.NewAnthropicClient(httpClient, apiURL, apiKey, model), nil
+	case domain.LLMProviderOllama:
+		return infrastructure.NewOllamaClient(httpClient, apiURL, apiKey, model), nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider %q", provider)
+	}
+}
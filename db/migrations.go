@@ -0,0 +1,9 @@
+// Package db embeds the SQL migrations, so the binary can migrate the database it is deployed with
+package db
+
+import "embed"
+
+// Migrations holds the numbered up and down migrations, e.g. 0002_add_column.up.sql
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS public.collection_translations;
+DROP TABLE IF EXISTS public.collections;
+DROP TABLE IF EXISTS public.users;
+DROP TABLE IF EXISTS public.translations;
@@ -1,7 +1,4 @@
-GRANT ALL PRIVILEGES ON DATABASE postgres TO admin;
-GRANT ALL PRIVILEGES ON SCHEMA public TO admin;
-GRANT ALL PRIVILEGES ON TABLE public.translations TO admin;
-
+-- Baseline schema, written to be a no-op on databases created by the former db/init.sql
 CREATE TABLE IF NOT EXISTS public.translations (
     id SERIAL PRIMARY KEY,
     lexical_item VARCHAR(255) NOT NULL,
@@ -13,7 +10,8 @@ CREATE TABLE IF NOT EXISTS public.translations (
     translated_meaning VARCHAR(255) NOT NULL,
     translated_examples VARCHAR(255)[]
 );
-CREATE INDEX idx_lexical_item ON translations (lexical_item);
+CREATE INDEX IF NOT EXISTS idx_lexical_item ON translations (lexical_item);
+CREATE INDEX IF NOT EXISTS idx_translations_lookup ON translations (lower(lexical_item), translated_from, translated_to);
 
 CREATE TABLE IF NOT EXISTS public.users (
     id SERIAL PRIMARY KEY,
@@ -21,29 +19,22 @@ CREATE TABLE IF NOT EXISTS public.users (
     password VARCHAR(255) NOT NULL,
     refresh_token VARCHAR(255)
 );
-CREATE INDEX idx_user_name ON users (user_name);
+CREATE INDEX IF NOT EXISTS idx_user_name ON users (user_name);
 
--- Create collections table
 CREATE TABLE IF NOT EXISTS public.collections (
     id SERIAL PRIMARY KEY,
     collection_name VARCHAR(255) NOT NULL,
     user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE
 );
+CREATE INDEX IF NOT EXISTS idx_user_id_collection ON collections (user_id);
 
--- Create index on user_id column in collections table
-CREATE INDEX idx_user_id_collection ON collections (user_id);
-
--- Create collection_translations join table
 CREATE TABLE IF NOT EXISTS public.collection_translations (
     id SERIAL PRIMARY KEY,
     collection_id INT NOT NULL REFERENCES public.collections(id) ON DELETE CASCADE,
     translation_id INT NOT NULL REFERENCES public.translations(id) ON DELETE CASCADE
 );
+CREATE INDEX IF NOT EXISTS idx_collection_id ON collection_translations (collection_id);
+CREATE INDEX IF NOT EXISTS idx_translation_id ON collection_translations (translation_id);
 
--- Create index on collection_id and translation_id columns in collection_translations table
-CREATE INDEX idx_collection_id ON collection_translations (collection_id);
-CREATE INDEX idx_translation_id ON collection_translations (translation_id);
-
--- Add due timestamp for flesh cards functionality
-ALTER TABLE public.collection_translations
-ADD COLUMN due TIMESTAMP;
\ No newline at end of file
+-- Due timestamp for flesh cards functionality
+ALTER TABLE public.collection_translations ADD COLUMN IF NOT EXISTS due TIMESTAMP;
@@ -0,0 +1,11 @@
+ALTER TABLE public.collections
+    DROP COLUMN scheduler;
+
+ALTER TABLE public.collection_translations
+    DROP COLUMN ease,
+    DROP COLUMN interval_days,
+    DROP COLUMN reps,
+    DROP COLUMN lapses,
+    DROP COLUMN stability,
+    DROP COLUMN difficulty,
+    DROP COLUMN last_review;
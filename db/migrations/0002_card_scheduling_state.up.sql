@@ -0,0 +1,18 @@
+ALTER TABLE public.collection_translations
+    ADD COLUMN ease DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    ADD COLUMN interval_days INT NOT NULL DEFAULT 0,
+    ADD COLUMN reps INT NOT NULL DEFAULT 0,
+    ADD COLUMN lapses INT NOT NULL DEFAULT 0,
+    ADD COLUMN stability DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN difficulty DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN last_review TIMESTAMP;
+
+-- Cards rated with the fixed intervals keep their interval as the starting point of the scheduler
+UPDATE public.collection_translations
+SET reps = 1,
+    interval_days = GREATEST(1, EXTRACT(DAY FROM due - NOW())::INT),
+    last_review = NOW()
+WHERE due > NOW();
+
+ALTER TABLE public.collections
+    ADD COLUMN scheduler VARCHAR(20) NOT NULL DEFAULT 'sm2';
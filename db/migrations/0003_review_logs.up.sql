@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS public.review_logs (
+    id BIGSERIAL PRIMARY KEY,
+    collection_translation_id INT NOT NULL REFERENCES public.collection_translations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    grade SMALLINT NOT NULL,
+    prev_due TIMESTAMP,
+    next_due TIMESTAMP,
+    -- state of the card before the review, needed to tell new cards from reviews and to optimize FSRS
+    prev_reps INT NOT NULL,
+    prev_interval_days INT NOT NULL,
+    -- state of the card after the review
+    interval_days INT NOT NULL,
+    ease DOUBLE PRECISION NOT NULL,
+    stability DOUBLE PRECISION NOT NULL,
+    difficulty DOUBLE PRECISION NOT NULL,
+    -- days since the previous review, NULL for the first one
+    elapsed_days DOUBLE PRECISION,
+    duration_ms INT,
+    reviewed_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_review_logs_card ON review_logs (collection_translation_id, reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_review_logs_user ON review_logs (user_id, reviewed_at);
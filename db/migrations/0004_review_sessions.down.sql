@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.review_session_cards;
+DROP TABLE IF EXISTS public.review_sessions;
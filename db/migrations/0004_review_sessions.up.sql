@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS public.review_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    collection_ids INT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+);
+CREATE INDEX IF NOT EXISTS idx_review_sessions_user_id ON review_sessions (user_id);
+
+-- The queue of a session, cards answered with Again are moved to its end
+CREATE TABLE IF NOT EXISTS public.review_session_cards (
+    session_id INT NOT NULL REFERENCES public.review_sessions(id) ON DELETE CASCADE,
+    collection_translation_id INT NOT NULL REFERENCES public.collection_translations(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    is_new BOOLEAN NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    last_answered_at TIMESTAMP,
+    PRIMARY KEY (session_id, collection_translation_id)
+);
+CREATE INDEX IF NOT EXISTS idx_review_session_cards_position ON review_session_cards (session_id, done, position);
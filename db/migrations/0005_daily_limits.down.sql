@@ -0,0 +1,7 @@
+ALTER TABLE public.users
+    DROP COLUMN IF EXISTS day_rollover_hour,
+    DROP COLUMN IF EXISTS timezone;
+
+ALTER TABLE public.collections
+    DROP COLUMN IF EXISTS reviews_per_day,
+    DROP COLUMN IF EXISTS new_cards_per_day;
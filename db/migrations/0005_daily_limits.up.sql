@@ -0,0 +1,8 @@
+ALTER TABLE public.collections
+    ADD COLUMN IF NOT EXISTS new_cards_per_day INT NOT NULL DEFAULT 20,
+    ADD COLUMN IF NOT EXISTS reviews_per_day INT NOT NULL DEFAULT 200;
+
+-- The study day of a user starts at day_rollover_hour in their time zone
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS day_rollover_hour SMALLINT NOT NULL DEFAULT 4;
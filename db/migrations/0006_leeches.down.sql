@@ -0,0 +1,8 @@
+ALTER TABLE public.collections
+    DROP COLUMN IF EXISTS leech_action,
+    DROP COLUMN IF EXISTS leech_threshold;
+
+ALTER TABLE public.collection_translations
+    DROP COLUMN IF EXISTS buried_until,
+    DROP COLUMN IF EXISTS suspended,
+    DROP COLUMN IF EXISTS tags;
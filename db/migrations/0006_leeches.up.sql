@@ -0,0 +1,9 @@
+ALTER TABLE public.collection_translations
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS buried_until TIMESTAMP;
+
+-- leech_threshold is the number of lapses a card becomes a leech at, 0 turns the detection off
+ALTER TABLE public.collections
+    ADD COLUMN IF NOT EXISTS leech_threshold INT NOT NULL DEFAULT 8,
+    ADD COLUMN IF NOT EXISTS leech_action VARCHAR(20) NOT NULL DEFAULT 'tag';
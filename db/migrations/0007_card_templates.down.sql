@@ -0,0 +1,5 @@
+ALTER TABLE public.collections DROP COLUMN IF EXISTS card_templates;
+
+DELETE FROM public.collection_translations WHERE template <> 'forward';
+DROP INDEX IF EXISTS idx_collection_translations_card;
+ALTER TABLE public.collection_translations DROP COLUMN IF EXISTS template;
@@ -0,0 +1,12 @@
+-- A translation saved twice before saving became idempotent keeps its first card only
+DELETE FROM public.collection_translations ct
+USING public.collection_translations first
+WHERE first.collection_id = ct.collection_id
+  AND first.translation_id = ct.translation_id
+  AND first.id < ct.id;
+
+-- Every saved translation has a card per template of its collection, each one is scheduled on its own
+ALTER TABLE public.collection_translations ADD COLUMN IF NOT EXISTS template VARCHAR(20) NOT NULL DEFAULT 'forward';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_translations_card ON collection_translations (collection_id, translation_id, template);
+
+ALTER TABLE public.collections ADD COLUMN IF NOT EXISTS card_templates TEXT[] NOT NULL DEFAULT '{forward}';
@@ -0,0 +1 @@
+ALTER TABLE public.translations DROP COLUMN IF EXISTS part_of_speech;
@@ -0,0 +1,2 @@
+-- Empty for the translations stored before the part of speech was requested from the LLM
+ALTER TABLE public.translations ADD COLUMN IF NOT EXISTS part_of_speech VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Imported translations come from the users' files, they aren't served to translation lookups
+ALTER TABLE public.translations ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.mochi_cards;
+ALTER TABLE public.collections DROP COLUMN IF EXISTS mochi_deck_id;
+ALTER TABLE public.users DROP COLUMN IF EXISTS mochi_api_token;
@@ -0,0 +1,15 @@
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS mochi_api_token VARCHAR(255);
+-- The Mochi deck the collection is synced to, a deck is created on the first sync when it isn't set
+ALTER TABLE public.collections ADD COLUMN IF NOT EXISTS mochi_deck_id VARCHAR(64);
+
+-- The Mochi card of every translation of a collection, kept after the translation leaves the collection
+-- until the card is deleted from Mochi
+CREATE TABLE IF NOT EXISTS public.mochi_cards (
+    collection_id INT NOT NULL REFERENCES public.collections(id) ON DELETE CASCADE,
+    translation_id INT NOT NULL REFERENCES public.translations(id) ON DELETE CASCADE,
+    mochi_card_id VARCHAR(64) NOT NULL,
+    mochi_deck_id VARCHAR(64) NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    synced_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (collection_id, translation_id)
+);
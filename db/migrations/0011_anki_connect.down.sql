@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.anki_cards;
+DROP TABLE IF EXISTS public.anki_notes;
+ALTER TABLE public.collections DROP COLUMN IF EXISTS anki_deck;
+ALTER TABLE public.users
+    DROP COLUMN IF EXISTS ankiconnect_key,
+    DROP COLUMN IF EXISTS ankiconnect_url;
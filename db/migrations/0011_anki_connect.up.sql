@@ -0,0 +1,19 @@
+ALTER TABLE public.users
+    ADD COLUMN IF NOT EXISTS ankiconnect_url VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS ankiconnect_key VARCHAR(255);
+-- The Anki deck the collection is synced to, it is named after the collection on the first sync
+ALTER TABLE public.collections ADD COLUMN IF NOT EXISTS anki_deck VARCHAR(255);
+
+-- The Anki note of every translation of a collection, its cards are linked in anki_cards
+CREATE TABLE IF NOT EXISTS public.anki_notes (
+    collection_id INT NOT NULL REFERENCES public.collections(id) ON DELETE CASCADE,
+    translation_id INT NOT NULL REFERENCES public.translations(id) ON DELETE CASCADE,
+    anki_note_id BIGINT NOT NULL,
+    PRIMARY KEY (collection_id, translation_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.anki_cards (
+    collection_translation_id INT PRIMARY KEY REFERENCES public.collection_translations(id) ON DELETE CASCADE,
+    anki_card_id BIGINT NOT NULL,
+    pushed_review TIMESTAMP
+);
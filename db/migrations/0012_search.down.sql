@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_translations_translated_meaning_trgm;
+DROP INDEX IF EXISTS idx_translations_translated_lexical_item_trgm;
+DROP INDEX IF EXISTS idx_translations_meaning_trgm;
+DROP INDEX IF EXISTS idx_translations_lexical_item_trgm;
+DROP INDEX IF EXISTS idx_translations_search_vector;
+DROP TRIGGER IF EXISTS translations_search_vector ON public.translations;
+DROP FUNCTION IF EXISTS public.translations_search_vector_trigger();
+ALTER TABLE public.translations DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS public.translation_search_vector(public.translations);
+DROP FUNCTION IF EXISTS public.search_config(TEXT);
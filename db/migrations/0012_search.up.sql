@@ -0,0 +1,58 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The text search configuration of a supported language, the languages Postgres has no stemmer for are only split into words
+CREATE OR REPLACE FUNCTION public.search_config(language TEXT) RETURNS regconfig
+LANGUAGE sql STABLE PARALLEL SAFE AS $$
+    SELECT CASE lower(language)
+        WHEN 'arabic' THEN 'pg_catalog.arabic'
+        WHEN 'dutch' THEN 'pg_catalog.dutch'
+        WHEN 'english' THEN 'pg_catalog.english'
+        WHEN 'french' THEN 'pg_catalog.french'
+        WHEN 'german' THEN 'pg_catalog.german'
+        WHEN 'greek' THEN 'pg_catalog.greek'
+        WHEN 'italian' THEN 'pg_catalog.italian'
+        WHEN 'portuguese' THEN 'pg_catalog.portuguese'
+        WHEN 'russian' THEN 'pg_catalog.russian'
+        WHEN 'spanish' THEN 'pg_catalog.spanish'
+        WHEN 'swedish' THEN 'pg_catalog.swedish'
+        WHEN 'turkish' THEN 'pg_catalog.turkish'
+        ELSE 'pg_catalog.simple'
+    END::regconfig
+$$;
+
+-- The lexical items weigh the most, then the meanings and then the examples, each side is stemmed in its language
+CREATE OR REPLACE FUNCTION public.translation_search_vector(t public.translations) RETURNS tsvector
+LANGUAGE sql STABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector(public.search_config(t.translated_from), coalesce(t.lexical_item, '')), 'A') ||
+           setweight(to_tsvector(public.search_config(t.translated_from), coalesce(t.meaning, '')), 'B') ||
+           setweight(to_tsvector(public.search_config(t.translated_from), coalesce(array_to_string(t.examples, ' '), '')), 'C') ||
+           setweight(to_tsvector(public.search_config(t.translated_to), coalesce(t.translated_lexical_item, '')), 'A') ||
+           setweight(to_tsvector(public.search_config(t.translated_to), coalesce(t.translated_meaning, '')), 'B') ||
+           setweight(to_tsvector(public.search_config(t.translated_to), coalesce(array_to_string(t.translated_examples, ' '), '')), 'C')
+$$;
+
+ALTER TABLE public.translations ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION public.translations_search_vector_trigger() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := public.translation_search_vector(NEW);
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS translations_search_vector ON public.translations;
+CREATE TRIGGER translations_search_vector
+    BEFORE INSERT OR UPDATE OF lexical_item, meaning, examples, translated_from, translated_to,
+        translated_lexical_item, translated_meaning, translated_examples
+    ON public.translations
+    FOR EACH ROW EXECUTE FUNCTION public.translations_search_vector_trigger();
+
+UPDATE public.translations t SET search_vector = public.translation_search_vector(t);
+
+CREATE INDEX IF NOT EXISTS idx_translations_search_vector ON translations USING GIN (search_vector);
+-- Typo tolerant matching of the lexical items and the meanings
+CREATE INDEX IF NOT EXISTS idx_translations_lexical_item_trgm ON translations USING GIN (lexical_item gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_translations_meaning_trgm ON translations USING GIN (meaning gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_translations_translated_lexical_item_trgm ON translations USING GIN (translated_lexical_item gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_translations_translated_meaning_trgm ON translations USING GIN (translated_meaning gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_collection_translations_created_at;
+ALTER TABLE public.collection_translations DROP COLUMN IF EXISTS created_at;
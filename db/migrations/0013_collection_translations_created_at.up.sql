@@ -0,0 +1,3 @@
+-- The cards saved before the column was added get the time of the migration
+ALTER TABLE public.collection_translations ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC');
+CREATE INDEX IF NOT EXISTS idx_collection_translations_created_at ON collection_translations (collection_id, created_at, id);
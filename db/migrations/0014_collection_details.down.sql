@@ -0,0 +1,7 @@
+ALTER TABLE public.collections
+    DROP COLUMN IF EXISTS archived,
+    DROP COLUMN IF EXISTS icon,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS default_translated_to,
+    DROP COLUMN IF EXISTS default_translated_from,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,8 @@
+ALTER TABLE public.collections
+    ADD COLUMN IF NOT EXISTS description VARCHAR(1000) NOT NULL DEFAULT '',
+    -- The languages the client translates to the collection by default, either both are set or none
+    ADD COLUMN IF NOT EXISTS default_translated_from VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS default_translated_to VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS color VARCHAR(7),
+    ADD COLUMN IF NOT EXISTS icon VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE public.collection_translations
+    DROP COLUMN IF EXISTS template_suspended;
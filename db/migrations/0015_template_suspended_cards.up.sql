@@ -0,0 +1,4 @@
+-- template_suspended marks the cards suspended because their template was removed from the collection,
+-- they are unsuspended when the template is added back
+ALTER TABLE public.collection_translations
+    ADD COLUMN IF NOT EXISTS template_suspended BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+-- quiz_results records the answers to the multiple-choice quizzes, unlike review_logs they don't reschedule the cards
+CREATE TABLE IF NOT EXISTS public.quiz_results (
+    id BIGSERIAL PRIMARY KEY,
+    collection_translation_id INT NOT NULL REFERENCES public.collection_translations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    correct BOOLEAN NOT NULL,
+    duration_ms INT,
+    answered_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_quiz_results_card ON quiz_results (collection_translation_id, answered_at);
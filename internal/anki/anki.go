@@ -0,0 +1,321 @@
+// Package anki writes collection translations as an Anki package (.apkg)
+package anki
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	_ "modernc.org/sqlite"
+)
+
+// AudioSource pronounces a lexical item, its audio is added to the Audio field of the note
+type AudioSource interface {
+	Stream(ctx context.Context, text, language string) (io.ReadCloser, error)
+}
+
+// Anki card types and queues
+const (
+	cardTypeNew    = 0
+	cardTypeReview = 2
+
+	queueSuspended = -1
+	queueNew       = 0
+	queueReview    = 2
+)
+
+const defaultFactor = 2500
+
+// note is a translation with the cards of it that are exported
+type note struct {
+	translation domain.Translation
+	cards       []domain.CollectionTranslation
+	audio       string
+}
+
+// WritePackage writes the cards as an Anki package with a deck named deckName, the scheduling state of the
+// reviewed cards is carried over. Each translation becomes a note, its forward, reverse and meaning cards
+// become the cards of the note, cloze cards are left out as they need the cloze note type.
+// The audio of the words is fetched from audio when it is not nil
+func WritePackage(ctx context.Context, w io.Writer, deckName string, cards []domain.CollectionTranslation, audio AudioSource, now time.Time) error {
+	dir, err := os.MkdirTemp("", "anki-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	notes := groupNotes(cards)
+	media := make(map[string]string)
+	if audio != nil {
+		for i := range notes {
+			name, err := writeAudio(ctx, dir, len(media), notes[i].translation, audio)
+			if err != nil {
+				return err
+			}
+			media[strconv.Itoa(len(media))] = name
+			notes[i].audio = name
+		}
+	}
+
+	collectionPath := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(ctx, collectionPath, deckName, notes, now); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	if err := addFile(archive, "collection.anki2", collectionPath); err != nil {
+		return err
+	}
+	mediaFile, err := archive.Create("media")
+	if err != nil {
+		return fmt.Errorf("failed to add media to package: %w", err)
+	}
+	if err := json.NewEncoder(mediaFile).Encode(media); err != nil {
+		return fmt.Errorf("failed to write media: %w", err)
+	}
+	for i := 0; i < len(media); i++ {
+		if err := addFile(archive, strconv.Itoa(i), filepath.Join(dir, strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func groupNotes(cards []domain.CollectionTranslation) []note {
+	var notes []note
+	index := make(map[int]int)
+	for _, card := range cards {
+		if TemplateOrd(card.Template) < 0 {
+			continue
+		}
+		i, ok := index[card.Translation.ID]
+		if !ok {
+			i = len(notes)
+			index[card.Translation.ID] = i
+			notes = append(notes, note{translation: card.Translation})
+		}
+		notes[i].cards = append(notes[i].cards, card)
+	}
+	return notes
+}
+
+func writeAudio(ctx context.Context, dir string, index int, t domain.Translation, audio AudioSource) (string, error) {
+	stream, err := audio.Stream(ctx, t.OriginalLexicalItem, t.TranslatedFrom)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch audio of %q: %w", t.OriginalLexicalItem, err)
+	}
+	defer stream.Close()
+	file, err := os.Create(filepath.Join(dir, strconv.Itoa(index)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, stream); err != nil {
+		return "", fmt.Errorf("failed to write audio of %q: %w", t.OriginalLexicalItem, err)
+	}
+	return fmt.Sprintf("smart-dictionary-%d.mp3", t.ID), nil
+}
+
+func writeCollection(ctx context.Context, path, deckName string, notes []note, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create collection schema: %w", err)
+	}
+
+	mod := now.Unix()
+	deckID := now.UnixMilli()
+	created := creationDay(notes, now)
+	models, _ := json.Marshal(map[string]any{strconv.Itoa(modelID): model(deckID, mod)})
+	decks, _ := json.Marshal(map[string]any{
+		"1":                           deck(1, "Default", mod),
+		strconv.FormatInt(deckID, 10): deck(deckID, deckName, mod),
+	})
+	dconf, _ := json.Marshal(map[string]any{"1": deckConf()})
+	conf, _ := json.Marshal(collectionConf(deckID))
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO col VALUES (1, ?, ?, ?, ?, 0, 0, 0, ?, ?, ?, ?, '{}')",
+		created.Unix(), now.UnixMilli(), now.UnixMilli(), schemaVersion, string(conf), string(models), string(decks), string(dconf),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+
+	id := now.UnixMilli()
+	for position, n := range notes {
+		id++
+		noteID := id
+		fields := noteFields(n)
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')",
+			noteID, guid(n.translation), modelID, mod, noteTags(n), strings.Join(fields, "\x1f"), fields[fieldWord], checksum(fields[fieldWord]),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write note: %w", err)
+		}
+		for _, card := range n.cards {
+			id++
+			cardType, queue, due, interval, data := schedule(card, position+1, created)
+			factor := defaultFactor
+			if card.Ease > 0 {
+				factor = int(card.Ease * 1000)
+			}
+			_, err = tx.ExecContext(
+				ctx,
+				"INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, ?)",
+				id, noteID, deckID, TemplateOrd(card.Template), mod, cardType, queue, due, interval, factor, card.Reps, card.Lapses, data,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to write card: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// schedule converts the state of a card, new cards are due by their position and reviewed cards by the day
+// they are due counted from the creation of the collection. FSRS memory state goes to the card data
+func schedule(card domain.CollectionTranslation, position int, created time.Time) (cardType, queue int, due int64, interval int, data string) {
+	if card.IsNew() || card.Due == nil {
+		cardType, queue, due = cardTypeNew, queueNew, int64(position)
+	} else {
+		cardType, queue = cardTypeReview, queueReview
+		due = int64(card.Due.Sub(created).Hours() / 24)
+		interval = max(card.IntervalDays, 1)
+	}
+	if card.Suspended {
+		queue = queueSuspended
+	}
+	if card.Stability > 0 {
+		memory, _ := json.Marshal(map[string]float64{"s": card.Stability, "d": card.Difficulty})
+		data = string(memory)
+	}
+	return cardType, queue, due, interval, data
+}
+
+// creationDay is the start of the day of the earliest due card, so that no card is due before the collection was created
+func creationDay(notes []note, now time.Time) time.Time {
+	earliest := now.UTC()
+	for _, n := range notes {
+		for _, card := range n.cards {
+			if card.Due != nil && card.Due.Before(earliest) {
+				earliest = card.Due.UTC()
+			}
+		}
+	}
+	return time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func noteFields(n note) []string {
+	fields := translationFields(n.translation)
+	if n.audio != "" {
+		fields[fieldAudio] = "[sound:" + n.audio + "]"
+	}
+	return fields
+}
+
+func translationFields(t domain.Translation) []string {
+	examples := make([]string, 0, len(t.OriginalExamples))
+	for _, example := range t.OriginalExamples {
+		examples = append(examples, html.EscapeString(example))
+	}
+	fields := make([]string, len(fieldNames))
+	fields[fieldWord] = html.EscapeString(t.OriginalLexicalItem)
+	fields[fieldMeaning] = html.EscapeString(t.OriginalMeaning)
+	fields[fieldExamples] = strings.Join(examples, "<br>")
+	fields[fieldTranslation] = html.EscapeString(t.TranslatedLexicalItem)
+	return fields
+}
+
+// NoteFields returns the fields of the note of the translation by their names, the audio is left empty
+func NoteFields(t domain.Translation) map[string]string {
+	fields := make(map[string]string, len(fieldNames))
+	for i, value := range translationFields(t) {
+		fields[fieldNames[i]] = value
+	}
+	return fields
+}
+
+// noteTags are the tags of the cards of the note, Anki keeps them space separated with a space around
+func noteTags(n note) string {
+	var tags []string
+	for _, card := range n.cards {
+		for _, tag := range card.Tags {
+			tag = strings.ReplaceAll(tag, " ", "_")
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// TemplateOrd is the ord of the Anki card of the template, -1 for the templates the note type doesn't have
+func TemplateOrd(template string) int {
+	switch template {
+	case domain.CardTemplateForward:
+		return 0
+	case domain.CardTemplateReverse:
+		return 1
+	case domain.CardTemplateMeaning:
+		return 2
+	}
+	return -1
+}
+
+// guid is derived from the translation so that importing the package again updates the notes
+func guid(t domain.Translation) string {
+	sum := sha1.Sum([]byte("smart-dictionary:" + strconv.Itoa(t.ID)))
+	return base64.RawStdEncoding.EncodeToString(sum[:8])
+}
+
+// checksum is the first 8 hex digits of the SHA1 of the sort field, Anki uses it to find duplicates
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(html.UnescapeString(field)))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func addFile(archive *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to package: %w", name, err)
+	}
+	if _, err := io.Copy(entry, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
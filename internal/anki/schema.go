@@ -0,0 +1,265 @@
+package anki
+
+// schema is the collection schema 11 of Anki, the one every Anki version imports
+const schema = `
+CREATE TABLE col (
+    id     INTEGER PRIMARY KEY,
+    crt    INTEGER NOT NULL,
+    mod    INTEGER NOT NULL,
+    scm    INTEGER NOT NULL,
+    ver    INTEGER NOT NULL,
+    dty    INTEGER NOT NULL,
+    usn    INTEGER NOT NULL,
+    ls     INTEGER NOT NULL,
+    conf   TEXT NOT NULL,
+    models TEXT NOT NULL,
+    decks  TEXT NOT NULL,
+    dconf  TEXT NOT NULL,
+    tags   TEXT NOT NULL
+);
+CREATE TABLE notes (
+    id    INTEGER PRIMARY KEY,
+    guid  TEXT NOT NULL,
+    mid   INTEGER NOT NULL,
+    mod   INTEGER NOT NULL,
+    usn   INTEGER NOT NULL,
+    tags  TEXT NOT NULL,
+    flds  TEXT NOT NULL,
+    sfld  INTEGER NOT NULL,
+    csum  INTEGER NOT NULL,
+    flags INTEGER NOT NULL,
+    data  TEXT NOT NULL
+);
+CREATE TABLE cards (
+    id     INTEGER PRIMARY KEY,
+    nid    INTEGER NOT NULL,
+    did    INTEGER NOT NULL,
+    ord    INTEGER NOT NULL,
+    mod    INTEGER NOT NULL,
+    usn    INTEGER NOT NULL,
+    type   INTEGER NOT NULL,
+    queue  INTEGER NOT NULL,
+    due    INTEGER NOT NULL,
+    ivl    INTEGER NOT NULL,
+    factor INTEGER NOT NULL,
+    reps   INTEGER NOT NULL,
+    lapses INTEGER NOT NULL,
+    left   INTEGER NOT NULL,
+    odue   INTEGER NOT NULL,
+    odid   INTEGER NOT NULL,
+    flags  INTEGER NOT NULL,
+    data   TEXT NOT NULL
+);
+CREATE TABLE revlog (
+    id      INTEGER PRIMARY KEY,
+    cid     INTEGER NOT NULL,
+    usn     INTEGER NOT NULL,
+    ease    INTEGER NOT NULL,
+    ivl     INTEGER NOT NULL,
+    lastIvl INTEGER NOT NULL,
+    factor  INTEGER NOT NULL,
+    time    INTEGER NOT NULL,
+    type    INTEGER NOT NULL
+);
+CREATE TABLE graves (
+    usn  INTEGER NOT NULL,
+    oid  INTEGER NOT NULL,
+    type INTEGER NOT NULL
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const schemaVersion = 11
+
+// the note type is shared by all the exports so that Anki updates it instead of adding a copy on every import
+const modelID = 1718000000000
+
+// ModelName is the name of the note type of the notes
+const ModelName = "Smart Dictionary"
+
+const (
+	fieldWord = iota
+	fieldMeaning
+	fieldExamples
+	fieldTranslation
+	fieldAudio
+)
+
+var fieldNames = []string{"Word", "Meaning", "Examples", "Translation", "Audio"}
+
+// templates of the note type, their order is the ord of the cards
+var templates = []struct {
+	name, front, back string
+	required          int
+}{
+	{
+		name:     "Forward",
+		front:    "{{Word}}<br>{{Audio}}",
+		back:     "{{FrontSide}}<hr id=answer>{{Translation}}<br><i>{{Meaning}}</i><br>{{Examples}}",
+		required: fieldWord,
+	},
+	{
+		name:     "Reverse",
+		front:    "{{Translation}}",
+		back:     "{{FrontSide}}<hr id=answer>{{Word}}<br>{{Audio}}<br><i>{{Meaning}}</i><br>{{Examples}}",
+		required: fieldTranslation,
+	},
+	{
+		name:     "Meaning",
+		front:    "{{Meaning}}",
+		back:     "{{FrontSide}}<hr id=answer>{{Word}}<br>{{Audio}}<br>{{Translation}}<br>{{Examples}}",
+		required: fieldMeaning,
+	},
+}
+
+// Template is a card template of the note type
+type Template struct {
+	Name  string
+	Front string
+	Back  string
+}
+
+// NoteType returns the field names, the card templates in the order of their ords and the styling of the note type
+func NoteType() ([]string, []Template, string) {
+	tmpls := make([]Template, 0, len(templates))
+	for _, template := range templates {
+		tmpls = append(tmpls, Template{Name: template.name, Front: template.front, Back: template.back})
+	}
+	return append([]string{}, fieldNames...), tmpls, css
+}
+
+const css = `.card {
+    font-family: arial;
+    font-size: 20px;
+    text-align: center;
+    color: black;
+    background-color: white;
+}`
+
+func collectionConf(deckID int64) map[string]any {
+	return map[string]any{
+		"activeDecks":   []int64{deckID},
+		"curDeck":       deckID,
+		"newSpread":     0,
+		"collapseTime":  1200,
+		"timeLim":       0,
+		"estTimes":      true,
+		"dueCounts":     true,
+		"curModel":      modelID,
+		"nextPos":       1,
+		"sortType":      "noteFld",
+		"sortBackwards": false,
+		"addToCur":      true,
+	}
+}
+
+func model(deckID, mod int64) map[string]any {
+	fields := make([]map[string]any, 0, len(fieldNames))
+	for i, name := range fieldNames {
+		fields = append(fields, map[string]any{
+			"name":   name,
+			"ord":    i,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   20,
+			"media":  []string{},
+		})
+	}
+	tmpls := make([]map[string]any, 0, len(templates))
+	req := make([]any, 0, len(templates))
+	for i, template := range templates {
+		tmpls = append(tmpls, map[string]any{
+			"name":  template.name,
+			"ord":   i,
+			"qfmt":  template.front,
+			"afmt":  template.back,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		})
+		req = append(req, []any{i, "any", []int{template.required}})
+	}
+	return map[string]any{
+		"id":        modelID,
+		"name":      ModelName,
+		"type":      0,
+		"mod":       mod,
+		"usn":       -1,
+		"sortf":     fieldWord,
+		"did":       deckID,
+		"tmpls":     tmpls,
+		"flds":      fields,
+		"css":       css,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []any{},
+		"req":       req,
+	}
+}
+
+func deck(id int64, name string, mod int64) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"name":      name,
+		"desc":      "",
+		"mod":       mod,
+		"usn":       -1,
+		"collapsed": false,
+		"newToday":  []int{0, 0},
+		"revToday":  []int{0, 0},
+		"lrnToday":  []int{0, 0},
+		"timeToday": []int{0, 0},
+		"dyn":       0,
+		"conf":      1,
+		"extendNew": 10,
+		"extendRev": 50,
+	}
+}
+
+func deckConf() map[string]any {
+	return map[string]any{
+		"id":       1,
+		"name":     "Default",
+		"mod":      0,
+		"usn":      0,
+		"maxTaken": 60,
+		"timer":    0,
+		"autoplay": true,
+		"replayq":  true,
+		"dyn":      false,
+		"new": map[string]any{
+			"delays":        []float64{1, 10},
+			"ints":          []int{1, 4, 7},
+			"initialFactor": 2500,
+			"perDay":        20,
+			"order":         1,
+			"separate":      true,
+			"bury":          true,
+		},
+		"rev": map[string]any{
+			"perDay":     200,
+			"ease4":      1.3,
+			"fuzz":       0.05,
+			"maxIvl":     36500,
+			"ivlFct":     1,
+			"minSpace":   1,
+			"bury":       true,
+			"hardFactor": 1.2,
+		},
+		"lapse": map[string]any{
+			"delays":      []float64{10},
+			"mult":        0,
+			"minInt":      1,
+			"leechFails":  8,
+			"leechAction": 0,
+		},
+	}
+}
@@ -0,0 +1,221 @@
+// Package answer checks a typed answer against the expected lexical item
+package answer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength bounds the typed answer, the distance is computed in quadratic time
+const MaxLength = 1000
+
+// minLengthLimit keeps some room for a wrong answer to a very short expected one
+const minLengthLimit = 64
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffOp is a run of characters of the character diff, inserted characters are the ones
+// missing from the typed answer and deleted characters are the ones it should not have
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type Result struct {
+	Correct bool `json:"correct"`
+	// Exact reports whether the answer matched without a typo
+	Exact    bool         `json:"exact"`
+	Expected string       `json:"expected"`
+	Given    string       `json:"given"`
+	Distance int          `json:"distance"`
+	Diff     []DiffOp     `json:"diff"`
+	Grade    domain.Grade `json:"grade"`
+}
+
+// TooLong tells whether the typed answer is too long to be checked against the expected one,
+// it may be up to 4 times as long as the expected answer and at most MaxLength runes
+func TooLong(expected, given string) bool {
+	limit := max(4*utf8.RuneCountInString(expected), minLengthLimit)
+	return utf8.RuneCountInString(strings.TrimSpace(given)) > min(limit, MaxLength)
+}
+
+// Check compares the typed answer to the expected one ignoring case, diacritics and punctuation,
+// a few typos are tolerated depending on the length. Expected can list alternatives separated
+// by commas, semicolons or slashes, the closest one is taken
+func Check(expected, given string) Result {
+	given = norm.NFC.String(strings.TrimSpace(given))
+	best := Result{Distance: -1}
+	for _, alternative := range alternatives(expected) {
+		normalizedExpected := Normalize(alternative)
+		distance := levenshtein([]rune(normalizedExpected), []rune(Normalize(given)))
+		if best.Distance >= 0 && distance >= best.Distance {
+			continue
+		}
+		best = Result{
+			Expected: alternative,
+			Given:    given,
+			Distance: distance,
+			Exact:    distance == 0,
+			Correct:  distance <= tolerance(normalizedExpected),
+		}
+	}
+	best.Diff = Diff(best.Expected, given)
+	best.Grade = suggestGrade(best)
+	return best
+}
+
+// Normalize folds the text for comparison: it is case folded, diacritics and punctuation are removed
+// and the spaces are collapsed
+func Normalize(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(cases.Fold().String(text)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			space = b.Len() > 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space {
+				b.WriteRune(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Diff returns the character diff that turns given into expected, characters that only differ
+// in case or diacritics are equal
+func Diff(expected, given string) []DiffOp {
+	e, g := []rune(expected), []rune(given)
+	ek, gk := foldRunes(e), foldRunes(g)
+
+	// dist[i][j] is the edit distance between e[i:] and g[j:]
+	dist := make([][]int, len(e)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(g)+1)
+	}
+	for i := len(e); i >= 0; i-- {
+		for j := len(g); j >= 0; j-- {
+			switch {
+			case i == len(e):
+				dist[i][j] = len(g) - j
+			case j == len(g):
+				dist[i][j] = len(e) - i
+			case ek[i] == gk[j]:
+				dist[i][j] = dist[i+1][j+1]
+			default:
+				dist[i][j] = 1 + min(dist[i+1][j], dist[i][j+1], dist[i+1][j+1])
+			}
+		}
+	}
+
+	var ops []DiffOp
+	add := func(op string, r rune) {
+		if len(ops) > 0 && ops[len(ops)-1].Op == op {
+			ops[len(ops)-1].Text += string(r)
+			return
+		}
+		ops = append(ops, DiffOp{Op: op, Text: string(r)})
+	}
+	i, j := 0, 0
+	for i < len(e) || j < len(g) {
+		switch {
+		case i < len(e) && j < len(g) && ek[i] == gk[j]:
+			add(DiffEqual, e[i])
+			i, j = i+1, j+1
+		case j < len(g) && (i == len(e) || dist[i][j] == 1+dist[i][j+1]):
+			add(DiffDelete, g[j])
+			j++
+		case i < len(e) && (j == len(g) || dist[i][j] == 1+dist[i+1][j]):
+			add(DiffInsert, e[i])
+			i++
+		default:
+			add(DiffDelete, g[j])
+			add(DiffInsert, e[i])
+			i, j = i+1, j+1
+		}
+	}
+	if ops == nil {
+		ops = []DiffOp{}
+	}
+	return ops
+}
+
+// tolerance is the number of typos accepted for the normalized expected answer, one per five characters up to three
+func tolerance(normalized string) int {
+	return min(len([]rune(normalized))/5, 3)
+}
+
+func suggestGrade(result Result) domain.Grade {
+	switch {
+	case result.Exact:
+		return domain.GradeGood
+	case result.Correct:
+		return domain.GradeHard
+	}
+	return domain.GradeAgain
+}
+
+func alternatives(expected string) []string {
+	parts := strings.FieldsFunc(expected, func(r rune) bool {
+		return r == ',' || r == ';' || r == '/'
+	})
+	result := []string{strings.TrimSpace(expected)}
+	if len(parts) < 2 {
+		return result
+	}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// foldRunes folds every rune by case and diacritics only, spaces and punctuation are kept
+// so that the diff shows them
+func foldRunes(runes []rune) []string {
+	folded := make([]string, len(runes))
+	for i, r := range runes {
+		var b strings.Builder
+		for _, f := range norm.NFD.String(cases.Fold().String(string(r))) {
+			if !unicode.Is(unicode.Mn, f) {
+				b.WriteRune(f)
+			}
+		}
+		folded[i] = b.String()
+	}
+	return folded
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
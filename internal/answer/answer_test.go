@@ -0,0 +1,160 @@
+package answer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		given    string
+		correct  bool
+		exact    bool
+		distance int
+		want     string
+		grade    domain.Grade
+	}{
+		{"exact", "house", "house", true, true, 0, "house", domain.GradeGood},
+		{"case and spaces", "House", "  hOUSE ", true, true, 0, "House", domain.GradeGood},
+		{"diacritics", "café", "cafe", true, true, 0, "café", domain.GradeGood},
+		{"decomposed diacritics", "Müller", "mu\u0308ller", true, true, 0, "Müller", domain.GradeGood},
+		{"punctuation", "don't", "dont!", true, true, 0, "don't", domain.GradeGood},
+		{"typo within tolerance", "elephant", "elephnt", true, false, 1, "elephant", domain.GradeHard},
+		{"typo over tolerance", "elephant", "elefant", false, false, 2, "elephant", domain.GradeAgain},
+		{"no typo allowed for a short word", "cat", "cut", false, false, 1, "cat", domain.GradeAgain},
+		{"comma alternative", "to run, to jog", "to jog", true, true, 0, "to jog", domain.GradeGood},
+		{"semicolon alternative", "дом; здание", "здание", true, true, 0, "здание", domain.GradeGood},
+		{"slash alternative", "colour/color", "color", true, true, 0, "color", domain.GradeGood},
+		{"all alternatives", "to run, to jog", "to run, to jog", true, true, 0, "to run, to jog", domain.GradeGood},
+		{"closest alternative with a typo", "automobile / car", "automobil", true, false, 1, "automobile", domain.GradeHard},
+		{"wrong", "house", "tree", false, false, 4, "house", domain.GradeAgain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.expected, tt.given)
+			if got.Correct != tt.correct || got.Exact != tt.exact || got.Distance != tt.distance {
+				t.Errorf("Check(%q, %q) = correct %v, exact %v, distance %d, want %v, %v, %d",
+					tt.expected, tt.given, got.Correct, got.Exact, got.Distance, tt.correct, tt.exact, tt.distance)
+			}
+			if got.Expected != tt.want {
+				t.Errorf("Check(%q, %q).Expected = %q, want %q", tt.expected, tt.given, got.Expected, tt.want)
+			}
+			if got.Given != norm.NFC.String(strings.TrimSpace(tt.given)) {
+				t.Errorf("Check(%q, %q).Given = %q", tt.expected, tt.given, got.Given)
+			}
+			if got.Grade != tt.grade {
+				t.Errorf("Check(%q, %q).Grade = %v, want %v", tt.expected, tt.given, got.Grade, tt.grade)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"House", "house"},
+		{"  Hello,  World! ", "hello world"},
+		{"Straße", "strasse"},
+		{"état-major", "etat major"},
+		{"snake_case", "snake case"},
+		{"Ёлка", "елка"},
+		{"...", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.text); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		expected string
+		given    string
+		want     []DiffOp
+	}{
+		{"house", "house", []DiffOp{{DiffEqual, "house"}}},
+		{"house", "hose", []DiffOp{{DiffEqual, "ho"}, {DiffInsert, "u"}, {DiffEqual, "se"}}},
+		{"cat", "cats", []DiffOp{{DiffEqual, "cat"}, {DiffDelete, "s"}}},
+		{"cat", "cut", []DiffOp{{DiffEqual, "c"}, {DiffDelete, "u"}, {DiffInsert, "a"}, {DiffEqual, "t"}}},
+		{"Café", "cafe", []DiffOp{{DiffEqual, "Café"}}},
+		{"ice cream", "icecream", []DiffOp{{DiffEqual, "ice"}, {DiffInsert, " "}, {DiffEqual, "cream"}}},
+		{"don't", "dont", []DiffOp{{DiffEqual, "don"}, {DiffInsert, "'"}, {DiffEqual, "t"}}},
+		{"a-b", "a b", []DiffOp{{DiffEqual, "a"}, {DiffDelete, " "}, {DiffInsert, "-"}, {DiffEqual, "b"}}},
+		{"", "", []DiffOp{}},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.expected, tt.given); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Diff(%q, %q) = %v, want %v", tt.expected, tt.given, got, tt.want)
+		}
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	tests := []struct {
+		normalized string
+		want       int
+	}{
+		{"", 0},
+		{"cat", 0},
+		{"house", 1},
+		{"elephant", 1},
+		{"hippopotamus", 2},
+		{"дворянство", 2},
+		{"internationalization", 3},
+		{"a very long phrase that is still three typos", 3},
+	}
+	for _, tt := range tests {
+		if got := tolerance(tt.normalized); got != tt.want {
+			t.Errorf("tolerance(%q) = %d, want %d", tt.normalized, got, tt.want)
+		}
+	}
+}
+
+func TestAlternatives(t *testing.T) {
+	tests := []struct {
+		expected string
+		want     []string
+	}{
+		{"house", []string{"house"}},
+		{" house ", []string{"house"}},
+		{"to run, to jog", []string{"to run, to jog", "to run", "to jog"}},
+		{"a; b/ c", []string{"a; b/ c", "a", "b", "c"}},
+		{"a;; b", []string{"a;; b", "a", "b"}},
+		{"house,", []string{"house,"}},
+	}
+	for _, tt := range tests {
+		if got := alternatives(tt.expected); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("alternatives(%q) = %q, want %q", tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	tests := []struct {
+		expected string
+		given    string
+		want     bool
+	}{
+		{"cat", strings.Repeat("a", 64), false},
+		{"cat", strings.Repeat("a", 65), true},
+		{strings.Repeat("a", 100), strings.Repeat("b", 400), false},
+		{strings.Repeat("a", 100), strings.Repeat("b", 401), true},
+		{strings.Repeat("a", 500), strings.Repeat("b", MaxLength+1), true},
+		{"cat", "  cat  ", false},
+	}
+	for _, tt := range tests {
+		if got := TooLong(tt.expected, tt.given); got != tt.want {
+			t.Errorf("TooLong(%d runes, %d runes) = %v, want %v", len(tt.expected), len(tt.given), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package cloze blanks a lexical item inside its example sentences
+package cloze
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/answer"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// Blank replaces the blanked words in the cloze text shown to the learner
+const Blank = "[...]"
+
+// maxGap is the number of words allowed between the words of a phrase, e.g. "give it up" for "give up"
+const maxGap = 2
+
+type token struct {
+	start, end int
+	normalized string
+}
+
+// Make blanks the lexical item in the first example that contains it. The inflected forms are only
+// matched for English, the lexical items of the other languages have to be in the example as they are.
+// False is returned when none of the examples contains the lexical item
+func Make(lexicalItem, language string, examples []string) (domain.Cloze, bool) {
+	words := strings.Fields(answer.Normalize(lexicalItem))
+	if len(words) == 0 {
+		return domain.Cloze{}, false
+	}
+	matchers := []func(word, token string) bool{isExact}
+	if strings.EqualFold(language, domain.LanguageEnglish) {
+		matchers = append(matchers, isForm)
+	}
+	// an exact match anywhere in the examples wins over an inflected one
+	for _, matches := range matchers {
+		for i, example := range examples {
+			spans := match(words, tokenize(example), matches)
+			if spans == nil {
+				continue
+			}
+			return build(example, i, spans), true
+		}
+	}
+	return domain.Cloze{}, false
+}
+
+// Anki renders the cloze with the cloze deletion syntax of Anki
+func Anki(c domain.Cloze) string {
+	return render(c, func(answer string) string {
+		return "{{c1::" + answer + "}}"
+	})
+}
+
+// AnkiNotes converts translations to a text file that Anki imports as cloze notes, the translations
+// without an example that contains their lexical item are skipped
+func AnkiNotes(ts []domain.Translation) string {
+	var result strings.Builder
+	result.WriteString("#separator:tab\n")
+	result.WriteString("#html:false\n")
+	result.WriteString("#notetype:Cloze\n")
+	for _, t := range ts {
+		c, ok := Make(t.OriginalLexicalItem, t.TranslatedFrom, t.OriginalExamples)
+		if !ok {
+			continue
+		}
+		extra := fmt.Sprintf("%s - %s (%s)", t.OriginalLexicalItem, t.TranslatedLexicalItem, t.OriginalMeaning)
+		result.WriteString(clean(Anki(c)) + "\t" + clean(extra) + "\n")
+	}
+	return result.String()
+}
+
+func build(example string, exampleIndex int, spans [][2]int) domain.Cloze {
+	c := domain.Cloze{Example: exampleIndex}
+	last := 0
+	for _, span := range spans {
+		c.Parts = append(c.Parts, domain.ClozePart{Text: example[last:span[0]]})
+		c.Parts = append(c.Parts, domain.ClozePart{Text: example[span[0]:span[1]], Blank: true})
+		c.Answers = append(c.Answers, example[span[0]:span[1]])
+		last = span[1]
+	}
+	c.Parts = append(c.Parts, domain.ClozePart{Text: example[last:]})
+	c.Text = render(c, func(string) string { return Blank })
+	return c
+}
+
+func render(c domain.Cloze, blank func(answer string) string) string {
+	var b strings.Builder
+	for _, part := range c.Parts {
+		if part.Blank {
+			b.WriteString(blank(part.Text))
+			continue
+		}
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+// match finds the tokens of the words in order, allowing maxGap tokens between them
+func match(words []string, tokens []token, matches func(word, token string) bool) [][2]int {
+	for start := range tokens {
+		if !matches(words[0], tokens[start].normalized) {
+			continue
+		}
+		spans := [][2]int{{tokens[start].start, tokens[start].end}}
+		next := start + 1
+		for _, word := range words[1:] {
+			found := -1
+			for j := next; j < len(tokens) && j <= next+maxGap; j++ {
+				if matches(word, tokens[j].normalized) {
+					found = j
+					break
+				}
+			}
+			if found < 0 {
+				spans = nil
+				break
+			}
+			// adjacent words are blanked together
+			if found == next {
+				spans[len(spans)-1][1] = tokens[found].end
+			} else {
+				spans = append(spans, [2]int{tokens[found].start, tokens[found].end})
+			}
+			next = found + 1
+		}
+		if spans != nil {
+			return spans
+		}
+	}
+	return nil
+}
+
+// inflections are the endings an English inflected form may add to the word
+var inflections = []string{"s", "es", "ed", "ing", "er", "est"}
+
+func isExact(word, token string) bool {
+	return word == token
+}
+
+// isForm reports whether token is the English word or the word with one of the inflections
+func isForm(word, token string) bool {
+	if word == token {
+		return true
+	}
+	w, t := []rune(word), []rune(token)
+	if len(w) < 3 {
+		return false
+	}
+	return isInflection(w, t)
+}
+
+// isInflection reports whether token is the word with an inflection, the spelling changes of
+// English are followed: "make" - "making", "love" - "loved", "study" - "studies", "stop" - "stopped"
+func isInflection(w, t []rune) bool {
+	common := 0
+	for common < len(w) && common < len(t) && w[common] == t[common] {
+		common++
+	}
+	dropped, tail := string(w[common:]), string(t[common:])
+	switch dropped {
+	case "":
+		last := w[len(w)-1]
+		if last == 'e' && isKnownInflection("e"+tail) {
+			return true
+		}
+		// a doubled final consonant
+		first, size := utf8.DecodeRuneInString(tail)
+		if len(tail) > size && first == last && !strings.ContainsRune("aeiouy", last) && isKnownInflection(tail[size:]) && tail[size:] != "s" {
+			return true
+		}
+		return isKnownInflection(tail)
+	case "e":
+		// the final e goes before an ending that starts with a vowel
+		return tail != "s" && isKnownInflection(tail)
+	case "y":
+		return strings.HasPrefix(tail, "i") && isKnownInflection(strings.TrimPrefix(tail, "i")) && tail != "is"
+	}
+	return false
+}
+
+func isKnownInflection(suffix string) bool {
+	for _, inflection := range inflections {
+		if suffix == inflection {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if normalized := answer.Normalize(text[start:end]); normalized != "" {
+			tokens = append(tokens, token{start: start, end: end, normalized: normalized})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '\'' || r == '’' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+func clean(text string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ").Replace(text)
+}
@@ -0,0 +1,75 @@
+package cloze
+
+import "testing"
+
+func TestIsForm(t *testing.T) {
+	tests := []struct {
+		word, token string
+		want        bool
+	}{
+		{"cat", "cat", true},
+		{"cat", "cats", true},
+		{"box", "boxes", true},
+		{"walk", "walked", true},
+		{"walk", "walking", true},
+		{"fast", "faster", true},
+		{"fast", "fastest", true},
+		{"make", "making", true},
+		{"love", "loved", true},
+		{"large", "larger", true},
+		{"study", "studies", true},
+		{"study", "studied", true},
+		{"stop", "stopped", true},
+		{"run", "running", true},
+		{"colour", "color", false},
+		{"colour", "colout", false},
+		{"there", "where", false},
+		{"horse", "house", false},
+		{"cat", "hat", false},
+		{"cat", "cut", false},
+		{"walk", "talk", false},
+		{"cat", "catch", false},
+		{"car", "cart", false},
+		{"art", "artist", false},
+		{"make", "maks", false},
+		{"study", "studis", false},
+		{"cats", "cat", false},
+		{"go", "goes", false},
+	}
+	for _, tt := range tests {
+		if got := isForm(tt.word, tt.token); got != tt.want {
+			t.Errorf("isForm(%q, %q) = %v, want %v", tt.word, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		lexicalItem string
+		language    string
+		examples    []string
+		want        string
+		ok          bool
+	}{
+		{"give up", "english", []string{"Never give it up."}, "Never [...] it [...].", true},
+		{"study", "english", []string{"She studied all night."}, "She [...] all night.", true},
+		{"cat", "english", []string{"He wore a hat.", "I'll catch it."}, "", false},
+		{"cat", "english", []string{"He wore a hat.", "Two cats slept."}, "Two [...] slept.", true},
+		{"there", "english", []string{"Where is it?"}, "", false},
+		{"horse", "english", []string{"The house is big."}, "", false},
+		// the exact word is preferred over an inflected form that comes first
+		{"walk", "english", []string{"He walked home, then took a walk."}, "He walked home, then took a [...].", true},
+		{"walk", "english", []string{"He walked home.", "Let's walk."}, "Let's [...].", true},
+		{"walk", "English", []string{"He walked home."}, "He [...] home.", true},
+		// the inflections are English only
+		{"walk", "polish", []string{"He walked home."}, "", false},
+		{"kot", "polish", []string{"Mam kota.", "To jest kot."}, "To jest [...].", true},
+		{"дом", "russian", []string{"Это мой дом."}, "Это мой [...].", true},
+	}
+	for _, tt := range tests {
+		c, ok := Make(tt.lexicalItem, tt.language, tt.examples)
+		if ok != tt.ok || (ok && c.Text != tt.want) {
+			t.Errorf("Make(%q, %q) = %q, %v, want %q, %v", tt.lexicalItem, tt.language, c.Text, ok, tt.want, tt.ok)
+		}
+	}
+}
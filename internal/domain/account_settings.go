@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// AccountSettings are the user's preferences that apply to all of their collections
+type AccountSettings struct {
+	Timezone string `json:"timezone"`
+	// DayRolloverHour is the local hour the study day starts at, daily limits are reset then
+	DayRolloverHour int `json:"dayRolloverHour"`
+}
+
+// AccountSettingsUpdate is a partial update of AccountSettings, nil fields are left as they are
+type AccountSettingsUpdate struct {
+	Timezone        *string `json:"timezone"`
+	DayRolloverHour *int    `json:"dayRolloverHour"`
+}
+
+// Location returns the user's time zone, UTC when it can not be loaded
+func (s AccountSettings) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// DayStart returns the start of the study day that now belongs to, in UTC
+func (s AccountSettings) DayStart(now time.Time) time.Time {
+	local := now.In(s.Location())
+	start := time.Date(local.Year(), local.Month(), local.Day(), s.DayRolloverHour, 0, 0, 0, local.Location())
+	if local.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start.UTC()
+}
+
+func IsTimezoneValid(timezone string) bool {
+	if timezone == "" || timezone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+func IsDayRolloverHourValid(hour int) bool {
+	return hour >= 0 && hour <= 23
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAnkiConnectNotConnected = errors.New("ankiconnect is not set up")
+
+// AnkiConnection is where the AnkiConnect add-on of the user listens, Key is set when the add-on requires one
+type AnkiConnection struct {
+	URL string `json:"url"`
+	Key string `json:"key,omitempty"`
+}
+
+// AnkiTarget is where a collection is synced to, Deck is empty until the first sync
+type AnkiTarget struct {
+	Connection     AnkiConnection
+	Deck           string
+	CollectionName string
+	// Scheduler is the scheduler of the collection, the reviews pulled from Anki update its memory state
+	Scheduler string
+}
+
+// AnkiCardLink ties a card of a collection to its Anki card
+type AnkiCardLink struct {
+	CollectionTranslationID int
+	CardID                  int64
+	// PushedReview is the last review of the card whose schedule Anki has, either pushed to it or pulled from it
+	PushedReview *time.Time
+}
+
+// AnkiNote is a note added through AnkiConnect
+type AnkiNote struct {
+	Deck   string
+	Model  string
+	Fields map[string]string
+	Tags   []string
+}
+
+// AnkiCardInfo is the state of an Anki card
+type AnkiCardInfo struct {
+	CardID int64 `json:"cardId"`
+	NoteID int64 `json:"note"`
+	Ord    int   `json:"ord"`
+	Reps   int   `json:"reps"`
+	Lapses int   `json:"lapses"`
+}
+
+// Anki review types
+const (
+	AnkiReviewLearn    = 0
+	AnkiReviewReview   = 1
+	AnkiReviewRelearn  = 2
+	AnkiReviewFiltered = 3
+)
+
+// AnkiReview is an entry of the review log of an Anki card, ID is the time of the review in milliseconds.
+// Interval is in days when positive and in seconds when negative, Factor is the ease in permille
+type AnkiReview struct {
+	ID       int64 `json:"id"`
+	Ease     int   `json:"ease"`
+	Interval int   `json:"ivl"`
+	Factor   int   `json:"factor"`
+	Time     int   `json:"time"`
+	Type     int   `json:"type"`
+}
+
+// IsAnswer tells a review answered with a button apart from the manual rescheduling Anki logs as well
+func (r AnkiReview) IsAnswer() bool {
+	return r.Ease >= int(GradeAgain) && r.Ease <= int(GradeEasy) && r.Type <= AnkiReviewFiltered
+}
+
+func (r AnkiReview) ReviewedAt() time.Time {
+	return time.UnixMilli(r.ID).UTC()
+}
+
+// Apply returns the state the review moved the card from prev to, the memory state of FSRS is kept as is,
+// the caller has to replay the grade through the scheduler of the collection to update it
+func (r AnkiReview) Apply(prev CardState) CardState {
+	next := prev
+	reviewedAt := r.ReviewedAt()
+	next.LastReview = &reviewedAt
+	next.Reps++
+	if Grade(r.Ease) == GradeAgain && r.Type == AnkiReviewReview {
+		next.Lapses++
+	}
+	if r.Factor > 0 {
+		next.Ease = float64(r.Factor) / 1000
+	}
+	due := reviewedAt.Add(time.Duration(-r.Interval) * time.Second)
+	next.IntervalDays = 0
+	if r.Interval > 0 {
+		next.IntervalDays = r.Interval
+		due = reviewedAt.AddDate(0, 0, r.Interval)
+	}
+	next.Due = &due
+	return next
+}
+
+type AnkiSyncResult struct {
+	Deck          string `json:"deck"`
+	NotesAdded    int    `json:"notesAdded"`
+	CardsLinked   int    `json:"cardsLinked"`
+	CardsPulled   int    `json:"cardsPulled"`
+	ReviewsPulled int    `json:"reviewsPulled"`
+	CardsPushed   int    `json:"cardsPushed"`
+	Unchanged     int    `json:"unchanged"`
+}
+
+// AnkiConnectError is a failed AnkiConnect action, either the add-on couldn't be reached or it replied with an error
+type AnkiConnectError struct {
+	Action string
+	Err    error
+}
+
+func (e *AnkiConnectError) Error() string {
+	return fmt.Sprintf("ankiconnect %s: %v", e.Action, e.Err)
+}
+
+func (e *AnkiConnectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+)
+
+// Card templates define what a card of a saved translation asks for
+const (
+	// CardTemplateForward shows the original lexical item and asks for its translation
+	CardTemplateForward = "forward"
+	// CardTemplateReverse shows the translated lexical item and asks for the original one
+	CardTemplateReverse = "reverse"
+	// CardTemplateMeaning shows the meaning and asks for the original lexical item
+	CardTemplateMeaning = "meaning"
+	// CardTemplateCloze shows an original example with the lexical item blanked and asks to fill it in,
+	// the card falls back to the meaning template when no example contains the lexical item
+	CardTemplateCloze = "cloze"
+)
+
+func IsCardTemplateSupported(template string) bool {
+	switch template {
+	case CardTemplateForward, CardTemplateReverse, CardTemplateMeaning, CardTemplateCloze:
+		return true
+	}
+	return false
+}
+
+// NormalizeCardTemplates returns the templates without duplicates,
+// false is returned when there are none or one of them is not supported
+func NormalizeCardTemplates(templates []string) ([]string, bool) {
+	normalized := make([]string, 0, len(templates))
+	for _, template := range templates {
+		if !IsCardTemplateSupported(template) {
+			return nil, false
+		}
+		if !slices.Contains(normalized, template) {
+			normalized = append(normalized, template)
+		}
+	}
+	return normalized, len(normalized) > 0
+}
+
+// Cloze is an example sentence with the lexical item blanked
+type Cloze struct {
+	// Text is the example with the blanked words replaced by a placeholder
+	Text    string      `json:"text"`
+	Answers []string    `json:"answers"`
+	Parts   []ClozePart `json:"parts"`
+	// Example is the index of the example in OriginalExamples
+	Example int `json:"example"`
+}
+
+type ClozePart struct {
+	Text  string `json:"text"`
+	Blank bool   `json:"blank,omitempty"`
+}
+
+// ExpectedAnswer returns what the card asks for by its template
+func (ct CollectionTranslation) ExpectedAnswer() string {
+	if ct.Cloze != nil {
+		return strings.Join(ct.Cloze.Answers, " ")
+	}
+	if ct.Template != CardTemplateForward {
+		return ct.Translation.OriginalLexicalItem
+	}
+	return ct.Translation.TranslatedLexicalItem
+}
+
+// Prompt returns what the card shows by its template
+func (ct CollectionTranslation) Prompt() string {
+	switch {
+	case ct.Cloze != nil:
+		return ct.Cloze.Text
+	case ct.Template == CardTemplateReverse:
+		return ct.Translation.TranslatedLexicalItem
+	case ct.Template == CardTemplateMeaning || ct.Template == CardTemplateCloze:
+		return ct.Translation.OriginalMeaning
+	}
+	return ct.Translation.OriginalLexicalItem
+}
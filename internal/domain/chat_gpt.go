@@ -1,5 +1,22 @@
 package domain
 
+type ChatGPTRequest struct {
+	Model          string                 `json:"model"`
+	Messages       []ChatGPTMessage       `json:"messages"`
+	ResponseFormat *ChatGPTResponseFormat `json:"response_format,omitempty"`
+}
+
+type ChatGPTResponseFormat struct {
+	Type       string             `json:"type"`
+	JSONSchema *ChatGPTJSONSchema `json:"json_schema,omitempty"`
+}
+
+type ChatGPTJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
+}
+
 type ChatGPTResponse struct {
 	Choices []ChatGPTChoice `json:"choices,omitempty"`
 }
@@ -9,5 +26,6 @@ type ChatGPTChoice struct {
 }
 
 type ChatGPTMessage struct {
+	Role    string `json:"role,omitempty"`
 	Content string `json:"content,omitempty"`
 }
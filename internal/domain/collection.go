@@ -1,16 +1,113 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+const DefaultCollectionName = "default"
+
+const MaxCardsPerDay = 9999
+
+var ErrCollectionNotFound = errors.New("collection not found")
+
+const (
+	MaxCollectionNameLength        = 255
+	MaxCollectionDescriptionLength = 1000
+	MaxCollectionIconLength        = 50
+)
 
 type Collection struct {
-	ID     int    `json:"id"`
-	Name   string `json:"name"`
-	UserID int    `json:"userId"`
+	ID             int            `json:"id"`
+	Name           string         `json:"name"`
+	UserID         int            `json:"userId"`
+	ReviewSettings ReviewSettings `json:"reviewSettings"`
+	Description    string         `json:"description"`
+	// DefaultTranslatedFrom and DefaultTranslatedTo are the languages translated to the collection by default, either both are set or none
+	DefaultTranslatedFrom string `json:"defaultTranslatedFrom,omitempty"`
+	DefaultTranslatedTo   string `json:"defaultTranslatedTo,omitempty"`
+	// Color is a hex color, e.g. #1e90ff
+	Color string `json:"color,omitempty"`
+	Icon  string `json:"icon,omitempty"`
+	// Archived collections are left out of the due cards and the review sessions
+	Archived bool `json:"archived"`
+	// Counts are only set on the listed collections and the updated one
+	Counts *CollectionCounts `json:"counts,omitempty"`
+}
+
+// CollectionCounts count the content of a collection
+type CollectionCounts struct {
+	// Words counts the translations, a translation has a card per template
+	Words int `json:"words"`
+	// Due counts the reviewed cards that are due and New the cards never reviewed, suspended and buried cards aren't counted
+	Due int `json:"due"`
+	New int `json:"new"`
+}
+
+// CollectionUpdate is a partial update of a collection, nil fields are left as they are.
+// An empty string clears the default language pair, the color and the icon
+type CollectionUpdate struct {
+	Name                  *string               `json:"name"`
+	Description           *string               `json:"description"`
+	DefaultTranslatedFrom *string               `json:"defaultTranslatedFrom"`
+	DefaultTranslatedTo   *string               `json:"defaultTranslatedTo"`
+	Color                 *string               `json:"color"`
+	Icon                  *string               `json:"icon"`
+	Archived              *bool                 `json:"archived"`
+	ReviewSettings        *ReviewSettingsUpdate `json:"reviewSettings"`
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func IsColorValid(color string) bool {
+	return hexColor.MatchString(color)
+}
+
+// ReviewSettings configures how the cards of a collection are reviewed
+type ReviewSettings struct {
+	Scheduler      string `json:"scheduler"`
+	NewCardsPerDay int    `json:"newCardsPerDay"`
+	ReviewsPerDay  int    `json:"reviewsPerDay"`
+	LeechThreshold int    `json:"leechThreshold"`
+	LeechAction    string `json:"leechAction"`
+	// CardTemplates are the cards created for every translation saved to the collection
+	CardTemplates []string `json:"cardTemplates"`
+}
+
+// ReviewSettingsUpdate is a partial update of ReviewSettings, nil fields are left as they are
+type ReviewSettingsUpdate struct {
+	Scheduler      *string `json:"scheduler"`
+	NewCardsPerDay *int    `json:"newCardsPerDay"`
+	ReviewsPerDay  *int    `json:"reviewsPerDay"`
+	LeechThreshold *int    `json:"leechThreshold"`
+	LeechAction    *string `json:"leechAction"`
+	// CardTemplates replace the templates of the collection when set, the cards of the
+	// added templates are created or unsuspended and the cards of the removed ones are suspended
+	CardTemplates []string `json:"cardTemplates"`
+}
+
+func IsCardsPerDayValid(limit int) bool {
+	return limit >= 0 && limit <= MaxCardsPerDay
 }
 
 type CollectionTranslation struct {
 	ID          int         `json:"id"`
 	Collection  Collection  `json:"collection"`
 	Translation Translation `json:"translation"`
-	Due         *time.Time  `json:"due,omitempty"`
+	Template    string      `json:"template"`
+	CardState
+	Tags      []string `json:"tags"`
+	Suspended bool     `json:"suspended"`
+	// BuriedUntil hides the card from the reviews until the time
+	BuriedUntil *time.Time `json:"buriedUntil,omitempty"`
+	// Cloze is set on the cloze cards that are served for a review
+	Cloze *Cloze `json:"cloze,omitempty"`
+	// CreatedAt is when the card was saved to the collection
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsReviewable tells whether the card may be reviewed at the time, suspended and buried cards are kept out of the reviews
+func (ct CollectionTranslation) IsReviewable(at time.Time) bool {
+	return !ct.Suspended && (ct.BuriedUntil == nil || !ct.BuriedUntil.After(at))
 }
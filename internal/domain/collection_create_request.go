@@ -2,4 +2,5 @@ package domain
 
 type CollectionCreateRequest struct {
 	CollectionName string `json:"collectionName"`
+	Scheduler      string `json:"scheduler"`
 }
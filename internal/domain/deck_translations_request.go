@@ -1,6 +1,124 @@
 package domain
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SortCreated      = "created"
+	SortDue          = "due"
+	SortAlphabetical = "alphabetical"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	// DueStateNew selects the translations with a card that was never reviewed
+	DueStateNew = "new"
+	// DueStateDue selects the translations with a reviewed card that is due
+	DueStateDue = "due"
+	// DueStateNotDue selects the translations whose cards are all reviewed and scheduled later
+	DueStateNotDue = "notDue"
+
+	DefaultTranslationsPageSize = 50
+	MaxTranslationsPageSize     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// GetCollectionsTranslationsRequest selects a page of the translations of a collection, the empty filters select everything
 type GetCollectionsTranslationsRequest struct {
 	CollectionID   int
+	UserID         int
 	TranslationIDs []int
+	TranslatedFrom string
+	TranslatedTo   string
+	DueState       string
+	Tag            string
+	// AddedSince and AddedUntil bound the time the translation was saved to the collection
+	AddedSince *time.Time
+	AddedUntil *time.Time
+	Sort       string
+	Order      string
+	// Cursor is where the previous page ended, nil for the first page
+	Cursor *TranslationsCursor
+	// Limit is the size of the page, 0 selects all the translations
+	Limit int
+	Now   time.Time
+}
+
+// DefaultOrder is the order of the sort when none is given, the newest translations go first
+func DefaultOrder(sort string) string {
+	if sort == SortCreated {
+		return OrderDesc
+	}
+	return OrderAsc
+}
+
+func IsTranslationsSortSupported(sort string) bool {
+	return sort == SortCreated || sort == SortDue || sort == SortAlphabetical
+}
+
+func IsDueStateSupported(state string) bool {
+	return state == DueStateNew || state == DueStateDue || state == DueStateNotDue
+}
+
+// TranslationsCursor is the position of the last translation of a page in its sort, it is only valid with the same sort and order
+type TranslationsCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	// ID is the id of the card the translation is listed by
+	ID        int        `json:"id"`
+	CreatedAt *time.Time `json:"c,omitempty"`
+	// Due is the earliest due of the reviewed cards of the translation, nil when none of them is reviewed
+	Due  *time.Time `json:"d,omitempty"`
+	Word string     `json:"w,omitempty"`
+}
+
+// Encode returns the cursor as an opaque URL safe string
+func (c TranslationsCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTranslationsCursor reads an encoded cursor and checks that it was made for the sort and the order
+func DecodeTranslationsCursor(value, sort, order string) (TranslationsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return TranslationsCursor{}, ErrInvalidCursor
+	}
+	var cursor TranslationsCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 || (cursor.Sort == SortCreated && cursor.CreatedAt == nil) {
+		return TranslationsCursor{}, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Order != order {
+		return TranslationsCursor{}, fmt.Errorf("%w: it was made for sort %s %s", ErrInvalidCursor, cursor.Sort, cursor.Order)
+	}
+	return cursor, nil
+}
+
+// CollectionTranslationsPage is a page of the translations of a collection, Total counts all the selected translations
+type CollectionTranslationsPage struct {
+	Items []CollectionTranslation `json:"items"`
+	Total int                     `json:"total"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ParseDateBound reads a bound of a time range, either a date or a time in RFC 3339.
+// A date of the upper bound includes the whole day
+func ParseDateBound(value string, upper bool) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		if upper {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a date (YYYY-MM-DD) nor a time (RFC 3339)", value)
 }
@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+// RatingType is the legacy fixed-interval rating, it is mapped to a Grade by RatingType.Grade
 type RatingType int
 
 const (
@@ -9,6 +15,81 @@ const (
 	RatingTwoMonth RatingType = 4
 )
 
+// Grade is the answer quality a scheduler works with
+type Grade int
+
+const (
+	GradeAgain Grade = 1
+	GradeHard  Grade = 2
+	GradeGood  Grade = 3
+	GradeEasy  Grade = 4
+)
+
+const (
+	SchedulerSM2     = "sm2"
+	SchedulerFSRS    = "fsrs"
+	DefaultScheduler = SchedulerSM2
+)
+
+var ErrCollectionTranslationNotFound = errors.New("collection translation not found")
+
+// ErrCardNotReviewable is returned when a suspended card or a card that is still buried is reviewed
+var ErrCardNotReviewable = errors.New("card is suspended or buried")
+
+// ErrReviewConflict is returned when the card has been reviewed by another request since it was read
+var ErrReviewConflict = errors.New("card has been reviewed in the meantime")
+
 type RateTranslationInput struct {
-	Rating RatingType `json:"rating"`
-}
\ No newline at end of file
+	// Rating is kept for the clients that don't send Grade yet
+	Rating RatingType `json:"rating,omitempty"`
+	Grade  Grade      `json:"grade,omitempty"`
+	// DurationMs is how long the user took to answer
+	DurationMs *int `json:"durationMs,omitempty"`
+}
+
+// TypedAnswerInput is the answer typed for a card, its grade is suggested by checking it
+type TypedAnswerInput struct {
+	Answer     string `json:"answer"`
+	DurationMs *int   `json:"durationMs,omitempty"`
+}
+
+// Grade maps the legacy ratings to grades, zero is returned for an unknown rating
+func (r RatingType) Grade() Grade {
+	switch r {
+	case RatingAgain:
+		return GradeAgain
+	case RatingWeek:
+		return GradeHard
+	case RatingMonth:
+		return GradeGood
+	case RatingTwoMonth:
+		return GradeEasy
+	}
+	return 0
+}
+
+func (g Grade) IsValid() bool {
+	return g >= GradeAgain && g <= GradeEasy
+}
+
+// CardState is the scheduling state of a collection translation,
+// SM-2 uses Ease and FSRS uses Stability and Difficulty, the rest is shared
+type CardState struct {
+	Due          *time.Time `json:"due,omitempty"`
+	Ease         float64    `json:"ease"`
+	IntervalDays int        `json:"intervalDays"`
+	Reps         int        `json:"reps"`
+	Lapses       int        `json:"lapses"`
+	Stability    float64    `json:"stability"`
+	Difficulty   float64    `json:"difficulty"`
+	LastReview   *time.Time `json:"lastReview,omitempty"`
+}
+
+// IsNew reports whether the card has never been reviewed
+func (s CardState) IsNew() bool {
+	return s.LastReview == nil
+}
+
+func IsSchedulerSupported(scheduler string) bool {
+	return scheduler == SchedulerSM2 || scheduler == SchedulerFSRS
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ImportModeKeep stores the rows as they are, every row needs a translation
+	ImportModeKeep = "keep"
+	// ImportModeEnrich fills the missing fields of the rows in with the translator
+	ImportModeEnrich = "enrich"
+)
+
+const (
+	ImportRowImported  = "imported"
+	ImportRowDuplicate = "duplicate"
+	ImportRowFailed    = "failed"
+)
+
+// ImportRowResult is the outcome of a row of an imported file, Line is the line of a text file or the note of an Anki package
+type ImportRowResult struct {
+	Line                    int    `json:"line"`
+	LexicalItem             string `json:"lexicalItem"`
+	Status                  string `json:"status"`
+	Error                   string `json:"error,omitempty"`
+	CollectionTranslationID int    `json:"collectionTranslationId,omitempty"`
+}
+
+type ImportResponse struct {
+	Format     string            `json:"format"`
+	Imported   int               `json:"imported"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Rows       []ImportRowResult `json:"rows"`
+}
+
+// MaxTranslationFieldLength is the length of the text columns of the translations table
+const MaxTranslationFieldLength = 255
+
+// ValidateImportedTranslation checks that the translation read from a file has a lexical item
+// and fits into the translations table
+func ValidateImportedTranslation(t Translation) error {
+	if strings.TrimSpace(t.OriginalLexicalItem) == "" {
+		return errors.New("word is missing")
+	}
+	fields := map[string]string{
+		"word":              t.OriginalLexicalItem,
+		"meaning":           t.OriginalMeaning,
+		"translation":       t.TranslatedLexicalItem,
+		"translatedMeaning": t.TranslatedMeaning,
+	}
+	for i, example := range t.OriginalExamples {
+		fields[fmt.Sprintf("examples[%d]", i)] = example
+	}
+	for i, example := range t.TranslatedExamples {
+		fields[fmt.Sprintf("translatedExamples[%d]", i)] = example
+	}
+	var errs []error
+	for name, value := range fields {
+		if utf8.RuneCountInString(value) > MaxTranslationFieldLength {
+			errs = append(errs, fmt.Errorf("%s is longer than %d characters", name, MaxTranslationFieldLength))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
@@ -22,3 +22,27 @@ var SupportedLanguages = map[string]struct{}{
 	"ukrainian":  {},
 	"vietnamese": {},
 }
+
+// LanguageCodes map the ISO 639-1 codes of the supported languages to their names
+var LanguageCodes = map[string]string{
+	"en": "english",
+	"ru": "russian",
+	"de": "german",
+	"ar": "arabic",
+	"zh": "chinese",
+	"nl": "dutch",
+	"fr": "french",
+	"el": "greek",
+	"he": "hebrew",
+	"it": "italian",
+	"ja": "japanese",
+	"ko": "korean",
+	"pl": "polish",
+	"pt": "portuguese",
+	"es": "spanish",
+	"sv": "swedish",
+	"th": "thai",
+	"tr": "turkish",
+	"uk": "ukrainian",
+	"vi": "vietnamese",
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "slices"
+
+const LeechTag = "leech"
+
+const (
+	LeechActionTag     = "tag"
+	LeechActionSuspend = "suspend"
+)
+
+const MaxLeechThreshold = 99
+
+func IsLeechActionSupported(action string) bool {
+	return action == LeechActionTag || action == LeechActionSuspend
+}
+
+func IsLeechThresholdValid(threshold int) bool {
+	return threshold >= 0 && threshold <= MaxLeechThreshold
+}
+
+// IsLeechLapse reports whether a card with lapses is a leech for the threshold, the card is
+// flagged when it reaches the threshold and then again every half of the threshold
+func IsLeechLapse(lapses, threshold int) bool {
+	if threshold <= 0 || lapses < threshold {
+		return false
+	}
+	step := max(threshold/2, 1)
+	return (lapses-threshold)%step == 0
+}
+
+// DetectLeech tags the card as a leech, and suspends it when the collection asks so,
+// if the last review added a lapse that makes it one
+func (ct *CollectionTranslation) DetectLeech(prevLapses int) bool {
+	settings := ct.Collection.ReviewSettings
+	if ct.Lapses <= prevLapses || !IsLeechLapse(ct.Lapses, settings.LeechThreshold) {
+		return false
+	}
+	if !slices.Contains(ct.Tags, LeechTag) {
+		ct.Tags = append(ct.Tags, LeechTag)
+	}
+	if settings.LeechAction == LeechActionSuspend {
+		ct.Suspended = true
+	}
+	return true
+}
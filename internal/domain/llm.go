@@ -0,0 +1,26 @@
+package domain
+
+const (
+	LLMProviderOpenAI    = "openai"
+	LLMProviderAnthropic = "anthropic"
+	LLMProviderOllama    = "ollama"
+)
+
+const (
+	LLMRoleUser      = "user"
+	LLMRoleAssistant = "assistant"
+)
+
+type LLMMessage struct {
+	Role    string
+	Content string
+}
+
+// LLMRequest is a provider agnostic completion request.
+// When Schema is set the provider is asked to reply with a JSON document matching it
+type LLMRequest struct {
+	System     string
+	Messages   []LLMMessage
+	SchemaName string
+	Schema     map[string]any
+}
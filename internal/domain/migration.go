@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrSchemaOutdated = errors.New("database schema is outdated, run `migrate up`")
+
+// ErrSchemaTooNew is returned when the database has been migrated by a newer version of the app
+var ErrSchemaTooNew = errors.New("database schema is newer than the app, upgrade the app")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
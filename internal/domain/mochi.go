@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrMochiNotConnected = errors.New("mochi api token is not set")
+
+// MochiTarget is where a collection is synced to, DeckID is empty until the collection is mapped to a deck
+type MochiTarget struct {
+	APIToken       string
+	DeckID         string
+	CollectionName string
+}
+
+// MochiCard is the Mochi card of a translation of a collection
+type MochiCard struct {
+	CollectionID  int
+	TranslationID int
+	CardID        string
+	DeckID        string
+	// ContentHash is the hash of the content last pushed, the card is only updated when it changes
+	ContentHash string
+	SyncedAt    time.Time
+}
+
+type MochiAccountRequest struct {
+	APIToken string `json:"apiToken"`
+}
+
+type MochiDeckRequest struct {
+	DeckID string `json:"deckId"`
+}
+
+type MochiSyncResult struct {
+	DeckID    string `json:"deckId"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Deleted   int    `json:"deleted"`
+}
+
+// MochiContent renders the translation as the Markdown of a Mochi card, a "---" line separates the sides of the card
+func MochiContent(t Translation) string {
+	var b strings.Builder
+	b.WriteString("# " + t.OriginalLexicalItem + "\n")
+	if t.PartOfSpeech != "" {
+		b.WriteString("\n*" + t.PartOfSpeech + "*\n")
+	}
+	b.WriteString("\n---\n\n")
+	b.WriteString("# " + t.TranslatedLexicalItem + "\n")
+	if t.OriginalMeaning != "" {
+		b.WriteString("\n" + t.OriginalMeaning + "\n")
+	}
+	writeMarkdownList(&b, t.OriginalExamples)
+	if t.TranslatedMeaning != "" {
+		b.WriteString("\n" + t.TranslatedMeaning + "\n")
+	}
+	writeMarkdownList(&b, t.TranslatedExamples)
+	return b.String()
+}
+
+func writeMarkdownList(b *strings.Builder, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	b.WriteString("\n")
+	for _, item := range items {
+		// a line break inside an item would end the list
+		b.WriteString("- " + strings.ReplaceAll(item, "\n", " ") + "\n")
+	}
+}
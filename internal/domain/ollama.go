@@ -0,0 +1,19 @@
+package domain
+
+type OllamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	// Format is either "json" or a JSON schema object
+	Format any `json:"format,omitempty"`
+}
+
+type OllamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type OllamaChatResponse struct {
+	Message OllamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+}
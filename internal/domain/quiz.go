@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+const (
+	DefaultQuizSize = 20
+	MaxQuizSize     = 100
+	// QuizChoices is the number of choices of a question, the answer included
+	QuizChoices = 4
+)
+
+// QuizQuestion asks to choose the answer of a card among distractors taken from other translations
+type QuizQuestion struct {
+	CollectionTranslationID int      `json:"collectionTranslationId"`
+	Template                string   `json:"template"`
+	Prompt                  string   `json:"prompt"`
+	Choices                 []string `json:"choices"`
+}
+
+type QuizAnswer struct {
+	CollectionTranslationID int    `json:"collectionTranslationId"`
+	Choice                  string `json:"choice"`
+	DurationMs              *int   `json:"durationMs"`
+}
+
+type QuizAnswersRequest struct {
+	Answers []QuizAnswer `json:"answers"`
+}
+
+type QuizAnswerResult struct {
+	CollectionTranslationID int    `json:"collectionTranslationId"`
+	Correct                 bool   `json:"correct"`
+	Expected                string `json:"expected,omitempty"`
+	Error                   string `json:"error,omitempty"`
+}
+
+// QuizResult is a recorded quiz answer, quizzes are a practice and don't reschedule the cards
+type QuizResult struct {
+	CollectionTranslationID int
+	UserID                  int
+	Correct                 bool
+	DurationMs              *int
+	AnsweredAt              time.Time
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// ReviewLog is a single answer given to a card
+type ReviewLog struct {
+	ID                      int64      `json:"id"`
+	CollectionTranslationID int        `json:"collectionTranslationId"`
+	UserID                  int        `json:"-"`
+	Grade                   Grade      `json:"grade"`
+	PrevDue                 *time.Time `json:"prevDue,omitempty"`
+	NextDue                 *time.Time `json:"nextDue,omitempty"`
+	PrevReps                int        `json:"prevReps"`
+	PrevIntervalDays        int        `json:"prevIntervalDays"`
+	IntervalDays            int        `json:"intervalDays"`
+	Ease                    float64    `json:"ease"`
+	Stability               float64    `json:"stability"`
+	Difficulty              float64    `json:"difficulty"`
+	ElapsedDays             *float64   `json:"elapsedDays,omitempty"`
+	DurationMs              *int       `json:"durationMs,omitempty"`
+	ReviewedAt              time.Time  `json:"reviewedAt"`
+}
+
+// NewReviewLog describes the review that moved a card from prev to next
+func NewReviewLog(collectionTranslationID, userID int, grade Grade, prev, next CardState, durationMs *int, reviewedAt time.Time) ReviewLog {
+	log := ReviewLog{
+		CollectionTranslationID: collectionTranslationID,
+		UserID:                  userID,
+		Grade:                   grade,
+		PrevDue:                 prev.Due,
+		NextDue:                 next.Due,
+		PrevReps:                prev.Reps,
+		PrevIntervalDays:        prev.IntervalDays,
+		IntervalDays:            next.IntervalDays,
+		Ease:                    next.Ease,
+		Stability:               next.Stability,
+		Difficulty:              next.Difficulty,
+		DurationMs:              durationMs,
+		ReviewedAt:              reviewedAt,
+	}
+	if prev.LastReview != nil {
+		elapsedDays := reviewedAt.Sub(*prev.LastReview).Hours() / 24
+		log.ElapsedDays = &elapsedDays
+	}
+	return log
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DefaultReviewBatchSize = 20
+	MaxReviewBatchSize     = 100
+)
+
+const (
+	ReviewAnswerApplied   = "applied"
+	ReviewAnswerDuplicate = "duplicate"
+	ReviewAnswerFailed    = "failed"
+)
+
+var ErrReviewSessionNotFound = errors.New("review session not found")
+
+type ReviewSessionRequest struct {
+	CollectionIDs []int `json:"collectionIds"`
+	BatchSize     int   `json:"batchSize"`
+	// NewLimit caps the number of new cards in the session on top of the daily limits of the collections
+	NewLimit *int `json:"newLimit"`
+}
+
+type ReviewSession struct {
+	ID            int                     `json:"id"`
+	CollectionIDs []int                   `json:"collectionIds"`
+	CreatedAt     time.Time               `json:"createdAt"`
+	Cards         []CollectionTranslation `json:"cards"`
+	Remaining     ReviewSessionRemaining  `json:"remaining"`
+}
+
+type ReviewSessionRemaining struct {
+	New    int `json:"new"`
+	Review int `json:"review"`
+	Total  int `json:"total"`
+}
+
+// ReviewSessionCard is a card in the queue of a session
+type ReviewSessionCard struct {
+	CollectionTranslationID int
+	CollectionID            int
+	Done                    bool
+	LastAnsweredAt          *time.Time
+}
+
+// ReviewAnswer is an answer given during a session, possibly offline, so ReviewedAt is set by the client
+type ReviewAnswer struct {
+	CollectionTranslationID int        `json:"collectionTranslationId"`
+	Grade                   Grade      `json:"grade"`
+	ReviewedAt              *time.Time `json:"reviewedAt"`
+	DurationMs              *int       `json:"durationMs"`
+}
+
+type ReviewAnswersRequest struct {
+	Answers []ReviewAnswer `json:"answers"`
+}
+
+type ReviewAnswerResult struct {
+	CollectionTranslationID int        `json:"collectionTranslationId"`
+	Status                  string     `json:"status"`
+	Error                   string     `json:"error,omitempty"`
+	Due                     *time.Time `json:"due,omitempty"`
+}
+
+type ReviewAnswersResponse struct {
+	Results   []ReviewAnswerResult   `json:"results"`
+	Remaining ReviewSessionRemaining `json:"remaining"`
+}
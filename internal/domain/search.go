@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// MaxSearchQueryLength bounds the query, longer ones can't match a field that is at most 255 characters anyway
+	MaxSearchQueryLength = 255
+)
+
+// Markers wrap the matched words in the highlights that the repository returns, they are turned into <mark> tags
+// after the text is escaped, so the highlights are safe to render as HTML
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchStop  = "\x03"
+)
+
+type SearchQuery struct {
+	UserID int
+	Query  string
+	Limit  int
+}
+
+// SearchCollection is a collection the found translation is saved to
+type SearchCollection struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SearchHighlights are the fields of the found translation as HTML, the matched words are wrapped in <mark>
+type SearchHighlights struct {
+	OriginalLexicalItem   string   `json:"originalLexicalItem"`
+	OriginalMeaning       string   `json:"originalMeaning"`
+	OriginalExamples      []string `json:"originalExamples"`
+	TranslatedLexicalItem string   `json:"translatedLexicalItem"`
+	TranslatedMeaning     string   `json:"translatedMeaning"`
+	TranslatedExamples    []string `json:"translatedExamples"`
+}
+
+type SearchResult struct {
+	Translation Translation        `json:"translation"`
+	Collections []SearchCollection `json:"collections"`
+	// Rank orders the results, the full text rank plus the similarity of the closest field to the query
+	Rank       float64          `json:"rank"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// HighlightHTML escapes the text and turns the match markers into <mark> tags
+func HighlightHTML(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer(SearchMatchStart, "<mark>", SearchMatchStop, "</mark>").Replace(text)
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// MatureIntervalDays is the interval from which a card counts as mature
+	MatureIntervalDays = 21
+	ForecastDays       = 30
+	DefaultStatsDays   = 365
+	MaxStatsDays       = 3650
+	DateLayout         = "2006-01-02"
+)
+
+type LearningStats struct {
+	DueToday      int        `json:"dueToday"`
+	DueTomorrow   int        `json:"dueTomorrow"`
+	New           int        `json:"new"`
+	Learning      int        `json:"learning"`
+	Mature        int        `json:"mature"`
+	ReviewsPerDay []DayCount `json:"reviewsPerDay"`
+	// RetentionRate is the share of passed reviews of already learned cards, nil when there were none
+	RetentionRate *float64   `json:"retentionRate"`
+	CurrentStreak int        `json:"currentStreak"`
+	LongestStreak int        `json:"longestStreak"`
+	Forecast      []DayCount `json:"forecast"`
+}
+
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type CardCounts struct {
+	DueToday    int
+	DueTomorrow int
+	New         int
+	Learning    int
+	Mature      int
+}
+
+// StatsQuery narrows the stats down to a collection, zero CollectionID means all unarchived collections of the user.
+// Days are study days in Location, they start at DayRolloverHour and are named by the date they start on
+type StatsQuery struct {
+	UserID          int
+	CollectionID    int
+	Days            int
+	Location        *time.Location
+	DayRolloverHour int
+	Now             time.Time
+}
+
+// DayStart returns the time the current study day has started at
+func (q StatsQuery) DayStart() time.Time {
+	now := q.Now.In(q.Location)
+	start := time.Date(now.Year(), now.Month(), now.Day(), q.DayRolloverHour, 0, 0, 0, q.Location)
+	if now.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// Today returns the date of the current study day, at midnight
+func (q StatsQuery) Today() time.Time {
+	start := q.DayStart()
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, q.Location)
+}
+
+// ReviewsPerDay returns a count for every one of the last days ending today, days without reviews included
+func ReviewsPerDay(reviews []DayCount, today time.Time, days int) []DayCount {
+	byDate := make(map[string]int, len(reviews))
+	for _, review := range reviews {
+		byDate[review.Date] = review.Count
+	}
+	result := make([]DayCount, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(DateLayout)
+		result = append(result, DayCount{Date: date, Count: byDate[date]})
+	}
+	return result
+}
+
+// Forecast spreads due cards over the next days starting today, overdue cards are due today
+func Forecast(due []DayCount, today time.Time, days int) []DayCount {
+	result := make([]DayCount, days)
+	for i := range result {
+		result[i].Date = today.AddDate(0, 0, i).Format(DateLayout)
+	}
+	todayDate := result[0].Date
+	for _, d := range due {
+		if d.Date <= todayDate {
+			result[0].Count += d.Count
+			continue
+		}
+		// dates are sorted the same way as strings
+		i := sort.Search(days, func(i int) bool { return result[i].Date >= d.Date })
+		if i < days && result[i].Date == d.Date {
+			result[i].Count += d.Count
+		}
+	}
+	return result
+}
+
+// Streaks returns the number of consecutive days with reviews ending today, or yesterday when
+// nothing has been reviewed today yet, and the longest run of such days
+func Streaks(reviews []DayCount, today time.Time) (current int, longest int) {
+	dates := make([]time.Time, 0, len(reviews))
+	for _, review := range reviews {
+		date, err := time.ParseInLocation(DateLayout, review.Date, today.Location())
+		if err != nil || review.Count == 0 {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	run := 0
+	for i, date := range dates {
+		if i > 0 && dates[i-1].AddDate(0, 0, 1).Equal(date) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	if len(dates) > 0 {
+		last := dates[len(dates)-1]
+		if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+			current = run
+		}
+	}
+	return current, longest
+}
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -15,11 +17,83 @@ type Translation struct {
 	TranslatedLexicalItem string   `json:"translatedLexicalItem"`
 	TranslatedMeaning     string   `json:"translatedMeaning"`
 	TranslatedExamples    []string `json:"translatedExamples"`
+	PartOfSpeech          string   `json:"partOfSpeech"`
+}
+
+// TranslationResponse is a translation with the outcome of saving it to a collection
+type TranslationResponse struct {
+	Translation
+	Saved                   bool   `json:"saved"`
+	CollectionID            int    `json:"collectionID,omitempty"`
+	CollectionTranslationID int    `json:"collectionTranslationID,omitempty"`
+	SaveError               string `json:"saveError,omitempty"`
+}
+
+// SavedTranslation identifies a translation stored in a collection
+type SavedTranslation struct {
+	CollectionID            int
+	CollectionTranslationID int
+}
+
+// PartsOfSpeech are the parts of speech a translation is classified by
+var PartsOfSpeech = []string{
+	"noun",
+	"verb",
+	"adjective",
+	"adverb",
+	"pronoun",
+	"preposition",
+	"conjunction",
+	"interjection",
+	"determiner",
+	"numeral",
+	"phrase",
+	"other",
+}
+
+// NormalizePartOfSpeech returns the part of speech in lower case, "other" when it is not one of PartsOfSpeech
+func NormalizePartOfSpeech(partOfSpeech string) string {
+	partOfSpeech = strings.ToLower(strings.TrimSpace(partOfSpeech))
+	if slices.Contains(PartsOfSpeech, partOfSpeech) {
+		return partOfSpeech
+	}
+	return "other"
+}
+
+// TranslationSchemaName and TranslationSchema describe the JSON document an LLM has to reply with,
+// it mirrors Translation without the database ID
+const TranslationSchemaName = "translation"
+
+var TranslationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"translatedFrom":        map[string]any{"type": "string"},
+		"translatedTo":          map[string]any{"type": "string"},
+		"originalLexicalItem":   map[string]any{"type": "string"},
+		"originalMeaning":       map[string]any{"type": "string"},
+		"originalExamples":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"translatedLexicalItem": map[string]any{"type": "string"},
+		"translatedMeaning":     map[string]any{"type": "string"},
+		"translatedExamples":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"partOfSpeech":          map[string]any{"type": "string", "enum": PartsOfSpeech},
+	},
+	"required": []string{
+		"translatedFrom",
+		"translatedTo",
+		"originalLexicalItem",
+		"originalMeaning",
+		"originalExamples",
+		"translatedLexicalItem",
+		"translatedMeaning",
+		"translatedExamples",
+		"partOfSpeech",
+	},
+	"additionalProperties": false,
 }
 
 func IsTranslationNilOrEmpty(t *Translation) bool {
 	if t == nil {
-		return false
+		return true
 	}
 	if t.OriginalLexicalItem == "" {
 		return true
@@ -42,22 +116,48 @@ func IsTranslationNilOrEmpty(t *Translation) bool {
 	return false
 }
 
-func ConvertTranslationToQuizletString(ts []Translation) string {
-	var result strings.Builder
-	for _, t := range ts {
-		result.WriteString(t.OriginalLexicalItem)
-		result.WriteString(";originalMeaning: " + t.OriginalMeaning + "\n")
-		result.WriteString("originalExamples:\n")
-		for i, example := range t.OriginalExamples {
-			result.WriteString(fmt.Sprintf("%d) %s\n", i+1, example))
-		}
-		result.WriteString("translatedLexicalItem: " + t.TranslatedLexicalItem + "\n")
-		result.WriteString("translatedMeaning: " + t.TranslatedMeaning + "\n")
-		result.WriteString("translatedExamples:\n")
-		for i, example := range t.TranslatedExamples {
-			result.WriteString(fmt.Sprintf("%d) %s\n", i+1, example))
+// ValidateTranslation checks that t is a complete translation of lexicalItem from translateFrom to translateTo,
+// all problems found are joined into the returned error
+func ValidateTranslation(t *Translation, lexicalItem, translateFrom, translateTo string) error {
+	if t == nil {
+		return errors.New("translation is empty")
+	}
+	var errs []error
+	if !strings.EqualFold(strings.TrimSpace(t.TranslatedFrom), translateFrom) {
+		errs = append(errs, fmt.Errorf("translatedFrom must be %q, got %q", translateFrom, t.TranslatedFrom))
+	}
+	if !strings.EqualFold(strings.TrimSpace(t.TranslatedTo), translateTo) {
+		errs = append(errs, fmt.Errorf("translatedTo must be %q, got %q", translateTo, t.TranslatedTo))
+	}
+	if !strings.EqualFold(strings.TrimSpace(t.OriginalLexicalItem), strings.TrimSpace(lexicalItem)) {
+		errs = append(errs, fmt.Errorf("originalLexicalItem must be %q, got %q", lexicalItem, t.OriginalLexicalItem))
+	}
+	if strings.TrimSpace(t.OriginalMeaning) == "" {
+		errs = append(errs, errors.New("originalMeaning is empty"))
+	}
+	if strings.TrimSpace(t.TranslatedLexicalItem) == "" {
+		errs = append(errs, errors.New("translatedLexicalItem is empty"))
+	}
+	if strings.TrimSpace(t.TranslatedMeaning) == "" {
+		errs = append(errs, errors.New("translatedMeaning is empty"))
+	}
+	if err := validateExamples("originalExamples", t.OriginalExamples); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateExamples("translatedExamples", t.TranslatedExamples); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func validateExamples(field string, examples []string) error {
+	if len(examples) == 0 {
+		return fmt.Errorf("%s is empty", field)
+	}
+	for i, example := range examples {
+		if strings.TrimSpace(example) == "" {
+			return fmt.Errorf("%s[%d] is empty", field, i)
 		}
-		result.WriteString("\n\n")
 	}
-	return result.String()
+	return nil
 }
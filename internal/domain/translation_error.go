@@ -0,0 +1,34 @@
+package domain
+
+import "fmt"
+
+type TranslationErrorCode string
+
+const (
+	TranslationErrorInvalidInput        TranslationErrorCode = "invalid_input"
+	TranslationErrorUnsupportedLanguage TranslationErrorCode = "unsupported_language"
+	TranslationErrorLexicalItemTooLong  TranslationErrorCode = "lexical_item_too_long"
+	TranslationErrorProviderUnavailable TranslationErrorCode = "provider_unavailable"
+	TranslationErrorMalformedResponse   TranslationErrorCode = "malformed_response"
+	TranslationErrorInvalidTranslation  TranslationErrorCode = "invalid_translation"
+	TranslationErrorStorageFailed       TranslationErrorCode = "storage_failed"
+)
+
+// TranslationError is returned by translators, Code is exposed to the API clients
+type TranslationError struct {
+	Code TranslationErrorCode
+	Err  error
+}
+
+func (e *TranslationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *TranslationError) Unwrap() error {
+	return e.Err
+}
+
+type TranslationErrorResponse struct {
+	Code    TranslationErrorCode `json:"code"`
+	Message string               `json:"message"`
+}
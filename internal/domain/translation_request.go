@@ -6,4 +6,5 @@ type TranslationRequest struct {
 	TranslateTo   string `json:"translateTo"`
 	SavingEnabled bool   `json:"savingEnabled"`
 	CollectionID  int    `json:"collectionID"`
+	ForceRefresh  bool   `json:"forceRefresh"`
 }
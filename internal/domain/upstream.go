@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while it is considered down
+var ErrCircuitOpen = errors.New("upstream is unavailable, circuit is open")
+
+// ErrAddressNotAllowed is returned without connecting when a user given URL resolves to a private address
+var ErrAddressNotAllowed = errors.New("address is not allowed")
+
+// UpstreamError is returned when a third party API replies with a non 2xx status
+type UpstreamError struct {
+	Host       string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("unexpected status code from %s: %d, response body: %s", e.Host, e.StatusCode, e.Body)
+}
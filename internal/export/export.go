@@ -0,0 +1,111 @@
+// Package export writes the cards of a collection in the formats of other flash card apps
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/anki"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	ProductQuizlet   = "quizlet"
+	ProductAnki      = "anki"
+	ProductAnkiCloze = "anki-cloze"
+	ProductCSV       = "csv"
+	ProductTSV       = "tsv"
+	ProductJSONLines = "jsonl"
+	ProductMarkdown  = "markdown"
+)
+
+// Exporter writes the cards of a collection in a format
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Export(ctx context.Context, w io.Writer, collection Collection, options Options) error
+}
+
+// Collection is what is exported, Cards are all the cards of every translation
+type Collection struct {
+	Name  string
+	Cards []domain.CollectionTranslation
+}
+
+// Options select what the text formats write, the zero value writes every field
+type Options struct {
+	// Fields are the columns, all of them when empty
+	Fields []string
+	// Separator replaces the separator of the format that has one to choose, e.g. ";" for CSV
+	Separator string
+	// Front and Back compose the two columns of a card out of fields, they replace Fields when set
+	Front []string
+	Back  []string
+	// Audio adds the pronunciation of the words to the formats that carry media
+	Audio bool
+	Now   time.Time
+}
+
+// Registry is the exporters by product
+type Registry map[string]Exporter
+
+// NewRegistry creates the registry of all the supported products, audio pronounces the words of the Anki packages
+func NewRegistry(audio anki.AudioSource) Registry {
+	return Registry{
+		ProductQuizlet:   quizletExporter{},
+		ProductAnki:      ankiExporter{audio: audio},
+		ProductAnkiCloze: ankiClozeExporter{},
+		ProductCSV:       csvExporter{},
+		ProductTSV:       tsvExporter{},
+		ProductJSONLines: jsonLinesExporter{},
+		ProductMarkdown:  markdownExporter{},
+	}
+}
+
+// Products returns the supported products in alphabetical order
+func (r Registry) Products() []string {
+	products := make([]string, 0, len(r))
+	for product := range r {
+		products = append(products, product)
+	}
+	sort.Strings(products)
+	return products
+}
+
+// Validate checks that the options only refer to supported fields and that the separator is a single character
+// other than a quote or a line break
+func (o Options) Validate() error {
+	for _, name := range append(append(append([]string{}, o.Fields...), o.Front...), o.Back...) {
+		if _, ok := fieldByName(name); !ok {
+			return fmt.Errorf("field %q is not supported, supported fields: %s", name, strings.Join(FieldNames(), ", "))
+		}
+	}
+	if (len(o.Front) == 0) != (len(o.Back) == 0) {
+		return fmt.Errorf("front and back must be set together")
+	}
+	if o.Separator != "" && len([]rune(o.Separator)) != 1 {
+		return fmt.Errorf("separator must be a single character")
+	}
+	if strings.ContainsAny(o.Separator, "\"\r\n") {
+		return fmt.Errorf("separator can't be a quote or a line break")
+	}
+	return nil
+}
+
+// translations returns a translation per note, the first card of each translation is taken
+func translations(cards []domain.CollectionTranslation) []domain.CollectionTranslation {
+	seen := make(map[int]bool)
+	var result []domain.CollectionTranslation
+	for _, card := range cards {
+		if seen[card.Translation.ID] {
+			continue
+		}
+		seen[card.Translation.ID] = true
+		result = append(result, card)
+	}
+	return result
+}
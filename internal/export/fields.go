@@ -0,0 +1,85 @@
+package export
+
+import (
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+type field struct {
+	name  string
+	value func(domain.CollectionTranslation) []string
+}
+
+var fields = []field{
+	{"word", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.OriginalLexicalItem} }},
+	{"meaning", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.OriginalMeaning} }},
+	{"examples", func(ct domain.CollectionTranslation) []string { return ct.Translation.OriginalExamples }},
+	{"translation", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.TranslatedLexicalItem} }},
+	{"translatedMeaning", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.TranslatedMeaning} }},
+	{"translatedExamples", func(ct domain.CollectionTranslation) []string { return ct.Translation.TranslatedExamples }},
+	{"partOfSpeech", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.PartOfSpeech} }},
+	{"from", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.TranslatedFrom} }},
+	{"to", func(ct domain.CollectionTranslation) []string { return []string{ct.Translation.TranslatedTo} }},
+	{"tags", func(ct domain.CollectionTranslation) []string { return ct.Tags }},
+}
+
+// FieldNames returns the names of the fields that can be exported
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func fieldByName(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// column is a column of a text format with the values of a translation
+type column struct {
+	name  string
+	value func(domain.CollectionTranslation) []string
+}
+
+// columns are the columns selected by the options: front and back, the fields or every field
+func columns(options Options) []column {
+	if len(options.Front) > 0 {
+		return []column{
+			{name: "front", value: compose(options.Front)},
+			{name: "back", value: compose(options.Back)},
+		}
+	}
+	names := options.Fields
+	if len(names) == 0 {
+		names = FieldNames()
+	}
+	result := make([]column, 0, len(names))
+	for _, name := range names {
+		f, _ := fieldByName(name)
+		result = append(result, column{name: f.name, value: f.value})
+	}
+	return result
+}
+
+// compose puts the values of the fields one after another, empty ones are skipped
+func compose(names []string) func(domain.CollectionTranslation) []string {
+	return func(ct domain.CollectionTranslation) []string {
+		var values []string
+		for _, name := range names {
+			f, _ := fieldByName(name)
+			for _, value := range f.value(ct) {
+				if strings.TrimSpace(value) != "" {
+					values = append(values, value)
+				}
+			}
+		}
+		return values
+	}
+}
@@ -0,0 +1,222 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/anki"
+	"github.com/bukhavtsov/artems-dictionary/internal/cloze"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// csvExporter writes RFC 4180 CSV with a header row, values of a list field are put on separate lines of the cell
+type csvExporter struct{}
+
+func (csvExporter) ContentType() string { return "text/csv" }
+func (csvExporter) Extension() string   { return "csv" }
+
+func (csvExporter) Export(_ context.Context, w io.Writer, collection Collection, options Options) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	if options.Separator != "" {
+		writer.Comma = []rune(options.Separator)[0]
+	}
+	if err := writeRows(collection, options, "\n", writer.Write); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// tsvExporter writes tab separated values with a header row, tabs and line breaks can't be in a value
+// so the values of a list field are separated with " | "
+type tsvExporter struct{}
+
+func (tsvExporter) ContentType() string { return "text/tab-separated-values" }
+func (tsvExporter) Extension() string   { return "tsv" }
+
+func (tsvExporter) Export(_ context.Context, w io.Writer, collection Collection, options Options) error {
+	replacer := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+	return writeRows(collection, options, " | ", func(row []string) error {
+		for i := range row {
+			row[i] = replacer.Replace(row[i])
+		}
+		_, err := io.WriteString(w, strings.Join(row, "\t")+"\n")
+		return err
+	})
+}
+
+// markdownExporter writes a table, the values of a list field are separated with line breaks
+type markdownExporter struct{}
+
+func (markdownExporter) ContentType() string { return "text/markdown" }
+func (markdownExporter) Extension() string   { return "md" }
+
+func (markdownExporter) Export(_ context.Context, w io.Writer, collection Collection, options Options) error {
+	replacer := strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+	header := true
+	return writeRows(collection, options, "<br>", func(row []string) error {
+		for i := range row {
+			row[i] = replacer.Replace(row[i])
+		}
+		line := "| " + strings.Join(row, " | ") + " |\n"
+		if header {
+			line += "|" + strings.Repeat(" --- |", len(row)) + "\n"
+			header = false
+		}
+		_, err := io.WriteString(w, line)
+		return err
+	})
+}
+
+// jsonLinesExporter writes a JSON object per translation, list fields are arrays
+type jsonLinesExporter struct{}
+
+func (jsonLinesExporter) ContentType() string { return "application/x-ndjson" }
+func (jsonLinesExporter) Extension() string   { return "jsonl" }
+
+func (jsonLinesExporter) Export(_ context.Context, w io.Writer, collection Collection, options Options) error {
+	cols := columns(options)
+	for _, ct := range translations(collection.Cards) {
+		// the keys are written one by one to keep the order of the fields
+		var line bytes.Buffer
+		line.WriteByte('{')
+		for i, col := range cols {
+			values := col.value(ct)
+			if values == nil {
+				values = []string{}
+			}
+			var value any = strings.Join(values, "\n")
+			if isList(col.name) {
+				value = values
+			}
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			if err := writeJSON(&line, col.name); err != nil {
+				return err
+			}
+			line.WriteByte(':')
+			if err := writeJSON(&line, value); err != nil {
+				return err
+			}
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("failed to write translation: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeJSON(buf *bytes.Buffer, value any) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode translation: %w", err)
+	}
+	// Encode ends the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// quizletExporter writes the text Quizlet imports, the term and the definition are separated with ";"
+// or the chosen separator and the cards with a blank line
+type quizletExporter struct{}
+
+func (quizletExporter) ContentType() string { return "text/plain" }
+func (quizletExporter) Extension() string   { return "txt" }
+
+func (quizletExporter) Export(_ context.Context, w io.Writer, collection Collection, options Options) error {
+	separator := ";"
+	if options.Separator != "" {
+		separator = options.Separator
+	}
+	// a line break inside a value would end the card and the separator in the term would end the term
+	replacement := ","
+	if separator == "," {
+		replacement = ";"
+	}
+	replacer := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", separator, replacement)
+	var result strings.Builder
+	for _, ct := range translations(collection.Cards) {
+		t := ct.Translation
+		result.WriteString(replacer.Replace(t.OriginalLexicalItem))
+		result.WriteString(separator + "originalMeaning: " + replacer.Replace(t.OriginalMeaning) + "\n")
+		result.WriteString("originalExamples:\n")
+		for i, example := range t.OriginalExamples {
+			result.WriteString(fmt.Sprintf("%d) %s\n", i+1, replacer.Replace(example)))
+		}
+		result.WriteString("translatedLexicalItem: " + replacer.Replace(t.TranslatedLexicalItem) + "\n")
+		result.WriteString("translatedMeaning: " + replacer.Replace(t.TranslatedMeaning) + "\n")
+		result.WriteString("translatedExamples:\n")
+		for i, example := range t.TranslatedExamples {
+			result.WriteString(fmt.Sprintf("%d) %s\n", i+1, replacer.Replace(example)))
+		}
+		result.WriteString("\n\n")
+	}
+	_, err := io.WriteString(w, result.String())
+	return err
+}
+
+// ankiExporter writes an Anki package with the scheduling state of the cards
+type ankiExporter struct {
+	audio anki.AudioSource
+}
+
+func (ankiExporter) ContentType() string { return "application/octet-stream" }
+func (ankiExporter) Extension() string   { return "apkg" }
+
+func (a ankiExporter) Export(ctx context.Context, w io.Writer, collection Collection, options Options) error {
+	var audio anki.AudioSource
+	if options.Audio {
+		audio = a.audio
+	}
+	return anki.WritePackage(ctx, w, collection.Name, collection.Cards, audio, options.Now)
+}
+
+// ankiClozeExporter writes the text Anki imports as cloze notes
+type ankiClozeExporter struct{}
+
+func (ankiClozeExporter) ContentType() string { return "text/plain" }
+func (ankiClozeExporter) Extension() string   { return "txt" }
+
+func (ankiClozeExporter) Export(_ context.Context, w io.Writer, collection Collection, _ Options) error {
+	var ts []domain.Translation
+	for _, ct := range translations(collection.Cards) {
+		ts = append(ts, ct.Translation)
+	}
+	_, err := io.WriteString(w, cloze.AnkiNotes(ts))
+	return err
+}
+
+// writeRows writes the header and a row per translation, the values of a column are joined with listSeparator
+func writeRows(collection Collection, options Options, listSeparator string, write func([]string) error) error {
+	cols := columns(options)
+	header := make([]string, 0, len(cols))
+	for _, col := range cols {
+		header = append(header, col.name)
+	}
+	if err := write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, ct := range translations(collection.Cards) {
+		row := make([]string, 0, len(cols))
+		for _, col := range cols {
+			row = append(row, strings.Join(col.value(ct), listSeparator))
+		}
+		if err := write(row); err != nil {
+			return fmt.Errorf("failed to write translation: %w", err)
+		}
+	}
+	return nil
+}
+
+func isList(name string) bool {
+	return name == "examples" || name == "translatedExamples" || name == "tags" || name == "front" || name == "back"
+}
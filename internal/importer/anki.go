@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	_ "modernc.org/sqlite"
+)
+
+// ankiModel is a note type of an Anki collection
+type ankiModel struct {
+	Type   int `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+const ankiModelCloze = 1
+
+// maxCollectionSize bounds the unpacked collection database, a small package may unpack into a huge file
+const maxCollectionSize = 200 << 20
+
+// ankiFieldColumns map the usual names of the fields of Anki notes to columns
+var ankiFieldColumns = map[string]string{
+	"word":        ColumnWord,
+	"front":       ColumnWord,
+	"term":        ColumnWord,
+	"expression":  ColumnWord,
+	"vocab":       ColumnWord,
+	"text":        ColumnWord,
+	"translation": ColumnTranslation,
+	"back":        ColumnTranslation,
+	"definition":  ColumnTranslation,
+	"meaning":     ColumnMeaning,
+	"examples":    ColumnExamples,
+	"example":     ColumnExamples,
+	"sentence":    ColumnExamples,
+}
+
+// parseAnki reads the notes of an Anki package (.apkg) or collection package (.colpkg), the fields of a note
+// are mapped by their names. Only the legacy collection format is supported, the newer one is compressed
+// with zstd and Anki writes the legacy one when "Support older Anki versions" is checked on export
+func parseAnki(ctx context.Context, data []byte, options Options) ([]Row, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	var collection *zip.File
+	hasLatest := false
+	for _, file := range archive.File {
+		switch file.Name {
+		case "collection.anki21":
+			collection = file
+		case "collection.anki2":
+			if collection == nil {
+				collection = file
+			}
+		case "collection.anki21b":
+			hasLatest = true
+		}
+	}
+	// packages of the latest format carry a legacy collection with a single note asking to update Anki
+	if hasLatest && (collection == nil || collection.Name == "collection.anki2") {
+		return nil, fmt.Errorf("%w: the package is in the latest Anki format, export it with \"Support older Anki versions\" checked", ErrMalformed)
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("%w: the package has no collection", ErrMalformed)
+	}
+
+	path, err := extract(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer db.Close()
+
+	var modelsJSON string
+	if err := db.QueryRowContext(ctx, "SELECT models FROM col").Scan(&modelsJSON); err != nil {
+		return nil, fmt.Errorf("%w: failed to read note types: %v", ErrMalformed, err)
+	}
+	var models map[string]ankiModel
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return nil, fmt.Errorf("%w: failed to read note types: %v", ErrMalformed, err)
+	}
+
+	notes, err := db.QueryContext(ctx, "SELECT mid, flds FROM notes ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read notes: %v", ErrMalformed, err)
+	}
+	defer notes.Close()
+
+	var rows []Row
+	for line := 1; notes.Next(); line++ {
+		var modelID int64
+		var fields string
+		if err := notes.Scan(&modelID, &fields); err != nil {
+			return nil, fmt.Errorf("%w: failed to read note: %v", ErrMalformed, err)
+		}
+		model := models[fmt.Sprint(modelID)]
+		values := strings.Split(fields, "\x1f")
+		var row Row
+		if model.Type == ankiModelCloze && len(options.Columns) == 0 {
+			row = Row{Line: line, Translation: clozeTranslation(values)}
+		} else {
+			cells := make([]string, len(values))
+			for i, value := range values {
+				cells[i] = plainText(value)
+			}
+			row = Row{Line: line, Translation: translationFromCells(cells, noteColumns(model, options.Columns))}
+		}
+		if isBlank([]string{row.Translation.OriginalLexicalItem, row.Translation.TranslatedLexicalItem}) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if err := notes.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read notes: %v", ErrMalformed, err)
+	}
+	return rows, nil
+}
+
+// extract unpacks the file into a temporary one, files larger than maxCollectionSize are rejected
+func extract(file *zip.File) (string, error) {
+	if file.UncompressedSize64 > maxCollectionSize {
+		return "", fmt.Errorf("%w: collection is larger than %d MB", ErrMalformed, maxCollectionSize>>20)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	defer src.Close()
+	dst, err := os.CreateTemp("", "anki-import-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer dst.Close()
+	// the size in the header may lie
+	written, err := io.Copy(dst, io.LimitReader(src, maxCollectionSize+1))
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if written > maxCollectionSize {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("%w: collection is larger than %d MB", ErrMalformed, maxCollectionSize>>20)
+	}
+	return dst.Name(), nil
+}
+
+// noteColumns are the columns of the fields of the note type, the given columns win over the names of the fields
+func noteColumns(model ankiModel, columns []string) []string {
+	if len(columns) > 0 {
+		return columns
+	}
+	fields := model.Fields
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Ord < fields[j].Ord })
+	result := make([]string, len(fields))
+	found := false
+	for i, field := range fields {
+		result[i] = ColumnSkip
+		if column, ok := ankiFieldColumns[strings.ToLower(strings.TrimSpace(field.Name))]; ok {
+			result[i] = column
+			found = found || column == ColumnWord
+		}
+	}
+	if !found {
+		return DefaultColumns
+	}
+	return result
+}
+
+var clozeDeletion = regexp.MustCompile(`\{\{c\d+::(.*?)(?:::.*?)?\}\}`)
+
+// clozeTranslation takes the answer of the first cloze deletion of the text as the word and the text
+// with the deletions filled in as its example, the second field of the note is taken as the translation
+func clozeTranslation(values []string) domain.Translation {
+	var t domain.Translation
+	text := plainText(values[0])
+	if match := clozeDeletion.FindStringSubmatch(text); match != nil {
+		t.OriginalLexicalItem = strings.TrimSpace(match[1])
+	}
+	if example := clozeDeletion.ReplaceAllString(text, "$1"); example != "" {
+		t.OriginalExamples = []string{example}
+	}
+	if len(values) > 1 {
+		t.TranslatedLexicalItem = plainText(values[1])
+	}
+	return t
+}
+
+var (
+	lineBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	tags       = regexp.MustCompile(`<[^>]*>`)
+	sounds     = regexp.MustCompile(`\[sound:[^\]]*\]`)
+)
+
+// plainText strips the HTML and the sounds of a field, line breaks are kept as new lines
+func plainText(field string) string {
+	field = lineBreaks.ReplaceAllString(field, "\n")
+	field = tags.ReplaceAllString(field, "")
+	field = sounds.ReplaceAllString(field, "")
+	return strings.TrimSpace(html.UnescapeString(field))
+}
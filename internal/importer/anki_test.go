@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/anki"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+func TestParseAnkiRoundTrip(t *testing.T) {
+	house := domain.Translation{
+		ID:                    10,
+		OriginalLexicalItem:   "house",
+		OriginalMeaning:       "a building <for> people & pets",
+		OriginalExamples:      []string{"Our house is old.", "They bought a house."},
+		TranslatedLexicalItem: "дом",
+	}
+	cat := domain.Translation{
+		ID:                    11,
+		OriginalLexicalItem:   "cat",
+		OriginalMeaning:       "a small animal",
+		TranslatedLexicalItem: "кот",
+	}
+	cards := []domain.CollectionTranslation{
+		{ID: 1, Template: domain.CardTemplateForward, Translation: house},
+		{ID: 2, Template: domain.CardTemplateReverse, Translation: house},
+		{ID: 3, Template: domain.CardTemplateForward, Translation: cat},
+	}
+	var buf bytes.Buffer
+	if err := anki.WritePackage(context.Background(), &buf, "Words", cards, nil, time.Now()); err != nil {
+		t.Fatalf("WritePackage() error = %v", err)
+	}
+	if format := Detect("Words.apkg", buf.Bytes()); format != FormatAnki {
+		t.Fatalf("Detect() = %q, want %q", format, FormatAnki)
+	}
+	rows, err := Parse(context.Background(), FormatAnki, buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []domain.Translation{house, cat}
+	if len(rows) != len(want) {
+		t.Fatalf("Parse() returned %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		want[i].ID = 0
+		if row.Line != i+1 || !reflect.DeepEqual(row.Translation, want[i]) {
+			t.Errorf("row %d = line %d, %+v, want %+v", i, row.Line, row.Translation, want[i])
+		}
+	}
+}
+
+func TestParseAnkiRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		message string
+	}{
+		{"latest format only", []string{"collection.anki21b"}, "latest Anki format"},
+		{"latest format with the legacy placeholder", []string{"collection.anki2", "collection.anki21b", "media"}, "latest Anki format"},
+		{"no collection", []string{"media"}, "no collection"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			archive := zip.NewWriter(&buf)
+			for _, name := range tt.files {
+				file, err := archive.Create(name)
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				file.Write([]byte("not a database"))
+			}
+			if err := archive.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			_, err := Parse(context.Background(), FormatAnki, buf.Bytes(), Options{})
+			if !errors.Is(err, ErrMalformed) || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Parse() error = %v, want ErrMalformed about %q", err, tt.message)
+			}
+		})
+	}
+
+	if _, err := Parse(context.Background(), FormatAnki, []byte("PK\x03\x04 not a zip"), Options{}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Parse() of a broken zip error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestClozeTranslation(t *testing.T) {
+	got := clozeTranslation([]string{"Never {{c1::give up::verb}} on <b>your</b> {{c2::dreams}}.", "сдаваться"})
+	want := domain.Translation{
+		OriginalLexicalItem:   "give up",
+		OriginalExamples:      []string{"Never give up on your dreams."},
+		TranslatedLexicalItem: "сдаваться",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clozeTranslation() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+// Package importer parses the words exported by other flash card apps into translations
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatTSV     = "tsv"
+	FormatQuizlet = "quizlet"
+	FormatAnki    = "anki"
+	// FormatKindle is the vocab.db of the Kindle Vocabulary Builder
+	FormatKindle = "kindle"
+)
+
+// Formats are the supported formats
+var Formats = []string{FormatCSV, FormatTSV, FormatQuizlet, FormatAnki, FormatKindle}
+
+// ErrMalformed is returned when the file can't be read in its format
+var ErrMalformed = errors.New("malformed file")
+
+// Row is a translation parsed out of a line of a text file or a note of an Anki package, Line counts from 1.
+// Any field of the translation but the original lexical item may be empty
+type Row struct {
+	Line        int
+	Translation domain.Translation
+	// Context are the sentences the word was met in, they go before the examples of the translation
+	Context []string
+}
+
+// Options tell how a file is read, the zero value detects everything
+type Options struct {
+	// Columns map the columns of CSV and TSV files and the fields of Anki notes to translation fields by position,
+	// "-" skips a column. When empty a header row of column names is used or else DefaultColumns
+	Columns []string
+	// Separator is the delimiter of CSV files and the separator of the term and the definition of Quizlet text
+	Separator string
+	// Kindle selects the lookups of a Kindle vocabulary
+	Kindle KindleFilter
+}
+
+const (
+	ColumnWord               = "word"
+	ColumnMeaning            = "meaning"
+	ColumnExamples           = "examples"
+	ColumnTranslation        = "translation"
+	ColumnTranslatedMeaning  = "translatedMeaning"
+	ColumnTranslatedExamples = "translatedExamples"
+	ColumnPartOfSpeech       = "partOfSpeech"
+	ColumnFrom               = "from"
+	ColumnTo                 = "to"
+	ColumnTags               = "tags"
+	ColumnSkip               = "-"
+)
+
+// Columns are the names of the columns, they match the fields of the exports so that an export can be imported back.
+// Tags are read but not imported
+var Columns = []string{
+	ColumnWord,
+	ColumnMeaning,
+	ColumnExamples,
+	ColumnTranslation,
+	ColumnTranslatedMeaning,
+	ColumnTranslatedExamples,
+	ColumnPartOfSpeech,
+	ColumnFrom,
+	ColumnTo,
+	ColumnTags,
+	ColumnSkip,
+}
+
+// DefaultColumns are the columns of files without a header, the term and the definition of the flash cards
+var DefaultColumns = []string{ColumnWord, ColumnTranslation, ColumnMeaning, ColumnExamples}
+
+// Validate checks that the options only refer to supported columns and that the separator is a single character
+func (o Options) Validate() error {
+	for _, column := range o.Columns {
+		if columnByName(column) == "" {
+			return fmt.Errorf("column %q is not supported, supported columns: %s", column, strings.Join(Columns, ", "))
+		}
+	}
+	if o.Separator != "" && utf8.RuneCountInString(o.Separator) != 1 {
+		return fmt.Errorf("separator must be a single character")
+	}
+	return nil
+}
+
+// Detect returns the format of the file by its name and content, empty when the format is unknown
+func Detect(filename string, data []byte) string {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatAnki
+	}
+	if bytes.HasPrefix(data, sqliteHeader) {
+		return FormatKindle
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".apkg", ".colpkg":
+		return FormatAnki
+	case ".tsv", ".tab":
+		return FormatTSV
+	case ".csv":
+		return FormatCSV
+	}
+	if len(data) == 0 || !utf8.Valid(data) {
+		return ""
+	}
+	text := string(data)
+	if strings.Contains(text, "\noriginalExamples:") {
+		return FormatQuizlet
+	}
+	firstLine, _, _ := strings.Cut(text, "\n")
+	if strings.Contains(firstLine, "\t") {
+		return FormatTSV
+	}
+	return FormatCSV
+}
+
+// Parse reads the rows of the file in the format, errors caused by the content of the file wrap ErrMalformed
+func Parse(ctx context.Context, format string, data []byte, options Options) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(data, options)
+	case FormatTSV:
+		return parseTSV(data, options), nil
+	case FormatQuizlet:
+		return parseQuizlet(data, options), nil
+	case FormatAnki:
+		return parseAnki(ctx, data, options)
+	case FormatKindle:
+		return parseKindle(ctx, data, options.Kindle)
+	}
+	return nil, fmt.Errorf("%w: format %q is not supported", ErrMalformed, format)
+}
+
+// parseRecords maps the cells of the records to translations, the first record is taken as a header
+// when it only has column names and no columns are given
+func parseRecords(records [][]string, lines []int, columns []string) []Row {
+	if len(columns) == 0 && len(records) > 0 {
+		if header, ok := headerColumns(records[0]); ok {
+			columns = header
+			records, lines = records[1:], lines[1:]
+		}
+	}
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	var rows []Row
+	for i, record := range records {
+		if isBlank(record) {
+			continue
+		}
+		rows = append(rows, Row{Line: lines[i], Translation: translationFromCells(record, columns)})
+	}
+	return rows
+}
+
+func headerColumns(record []string) ([]string, bool) {
+	columns := make([]string, 0, len(record))
+	for _, cell := range record {
+		column := columnByName(strings.TrimSpace(cell))
+		if column == "" {
+			return nil, false
+		}
+		columns = append(columns, column)
+	}
+	for _, column := range columns {
+		if column == ColumnWord {
+			return columns, true
+		}
+	}
+	return nil, false
+}
+
+// columnByName returns the column of the name ignoring the case, empty when it isn't a column
+func columnByName(name string) string {
+	for _, column := range Columns {
+		if strings.EqualFold(column, name) {
+			return column
+		}
+	}
+	return ""
+}
+
+func translationFromCells(cells []string, columns []string) domain.Translation {
+	var t domain.Translation
+	for i, column := range columns {
+		if i >= len(cells) {
+			break
+		}
+		setColumn(&t, column, cells[i])
+	}
+	return t
+}
+
+func setColumn(t *domain.Translation, column, value string) {
+	value = strings.TrimSpace(value)
+	switch column {
+	case ColumnWord:
+		t.OriginalLexicalItem = value
+	case ColumnMeaning:
+		t.OriginalMeaning = value
+	case ColumnExamples:
+		t.OriginalExamples = splitList(value)
+	case ColumnTranslation:
+		t.TranslatedLexicalItem = value
+	case ColumnTranslatedMeaning:
+		t.TranslatedMeaning = value
+	case ColumnTranslatedExamples:
+		t.TranslatedExamples = splitList(value)
+	case ColumnPartOfSpeech:
+		t.PartOfSpeech = value
+	case ColumnFrom:
+		t.TranslatedFrom = value
+	case ColumnTo:
+		t.TranslatedTo = value
+	}
+}
+
+// splitList splits the values of a list cell, the exports put them on separate lines or separate them with " | "
+func splitList(value string) []string {
+	var values []string
+	for _, line := range strings.Split(value, "\n") {
+		for _, item := range strings.Split(line, " | ") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
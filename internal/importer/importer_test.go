@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/export"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{"deck.apkg", "PK\x03\x04rest", FormatAnki},
+		{"deck.bin", "PK\x03\x04rest", FormatAnki},
+		{"backup.COLPKG", "anything", FormatAnki},
+		{"vocab.db", "SQLite format 3\x00rest", FormatKindle},
+		{"words", "SQLite format 3\x00rest", FormatKindle},
+		{"words.tsv", "house,дом", FormatTSV},
+		{"words.tab", "house\tдом", FormatTSV},
+		{"words.csv", "house\tдом", FormatCSV},
+		{"words.txt", "house\nhouse;originalMeaning: a building\noriginalExamples:\n", FormatQuizlet},
+		{"words.txt", "house\tдом\ncat,кот", FormatTSV},
+		{"words.txt", "house,дом\ncat\tкот", FormatCSV},
+		{"words.txt", "", ""},
+		{"words.txt", "\xff\xfe\x00h", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %q, want %q", tt.filename, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    []byte
+		options Options
+		want    []Row
+	}{
+		{
+			name:   "header-less CSV",
+			format: FormatCSV,
+			data:   readFixture(t, "headerless.csv"),
+			want: []Row{
+				{Line: 1, Translation: domain.Translation{
+					OriginalLexicalItem:   "house",
+					TranslatedLexicalItem: "дом",
+					OriginalMeaning:       "a building for people to live in",
+					OriginalExamples:      []string{"Our house is old."},
+				}},
+				{Line: 2, Translation: domain.Translation{
+					OriginalLexicalItem:   "give up",
+					TranslatedLexicalItem: "сдаваться",
+					OriginalMeaning:       "to stop trying",
+					OriginalExamples:      []string{"Never give up.", "Don't give up now."},
+				}},
+			},
+		},
+		{
+			name:   "CSV with a BOM, CRLF and a header",
+			format: FormatCSV,
+			data:   readFixture(t, "bom_crlf.csv"),
+			want: []Row{
+				{Line: 2, Translation: domain.Translation{OriginalLexicalItem: "cat", TranslatedLexicalItem: "кот", TranslatedFrom: "english", TranslatedTo: "russian"}},
+				{Line: 4, Translation: domain.Translation{OriginalLexicalItem: "dog", TranslatedLexicalItem: "собака", TranslatedFrom: "english", TranslatedTo: "russian"}},
+			},
+		},
+		{
+			name:    "CSV with a custom separator and a multiline cell",
+			format:  FormatCSV,
+			data:    readFixture(t, "semicolon.csv"),
+			options: Options{Separator: ";"},
+			want: []Row{
+				{Line: 2, Translation: domain.Translation{
+					OriginalLexicalItem:   "café",
+					TranslatedLexicalItem: "кафе",
+					OriginalExamples:      []string{"We met in a café.", "The café is closed."},
+				}},
+			},
+		},
+		{
+			name:    "CSV with given columns",
+			format:  FormatCSV,
+			data:    []byte("word,translation\nдом,house\n"),
+			options: Options{Columns: []string{ColumnTranslation, ColumnWord}},
+			want: []Row{
+				{Line: 1, Translation: domain.Translation{OriginalLexicalItem: "translation", TranslatedLexicalItem: "word"}},
+				{Line: 2, Translation: domain.Translation{OriginalLexicalItem: "house", TranslatedLexicalItem: "дом"}},
+			},
+		},
+		{
+			name:   "CSV header without a word column is data",
+			format: FormatCSV,
+			data:   []byte("translation,meaning\n"),
+			want: []Row{
+				{Line: 1, Translation: domain.Translation{OriginalLexicalItem: "translation", TranslatedLexicalItem: "meaning"}},
+			},
+		},
+		{
+			name:   "TSV with a header and a skipped column",
+			format: FormatTSV,
+			data:   readFixture(t, "words.tsv"),
+			want: []Row{
+				{Line: 2, Translation: domain.Translation{OriginalLexicalItem: "house", PartOfSpeech: "noun", TranslatedLexicalItem: "дом"}},
+				{Line: 4, Translation: domain.Translation{OriginalLexicalItem: "run", PartOfSpeech: "verb", TranslatedLexicalItem: "бегать"}},
+			},
+		},
+		{
+			name:   "Quizlet",
+			format: FormatQuizlet,
+			data:   readFixture(t, "quizlet.txt"),
+			want: []Row{
+				{Line: 1, Translation: domain.Translation{OriginalLexicalItem: "house", TranslatedLexicalItem: "дом"}},
+				{Line: 2, Translation: domain.Translation{OriginalLexicalItem: "cat", TranslatedLexicalItem: "кот"}},
+			},
+		},
+		{
+			name:    "Quizlet with a custom separator",
+			format:  FormatQuizlet,
+			data:    []byte("house: дом\r\ncat: кот"),
+			options: Options{Separator: ":"},
+			want: []Row{
+				{Line: 1, Translation: domain.Translation{OriginalLexicalItem: "house", TranslatedLexicalItem: "дом"}},
+				{Line: 2, Translation: domain.Translation{OriginalLexicalItem: "cat", TranslatedLexicalItem: "кот"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Parse(context.Background(), tt.format, tt.data, tt.options)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"not a Kindle vocabulary", FormatKindle, "SQLite format 3\x00rest"},
+		{"unsupported format", "xlsx", "house"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(context.Background(), tt.format, []byte(tt.data), Options{}); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: Parse() error = %v, want ErrMalformed", tt.name, err)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options Options
+		valid   bool
+	}{
+		{Options{}, true},
+		{Options{Columns: []string{"Word", "translation", "-"}, Separator: ";"}, true},
+		{Options{Columns: []string{"word", "audio"}}, false},
+		{Options{Separator: "::"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.options.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v.Validate() = %v, want valid %v", tt.options, err, tt.valid)
+		}
+	}
+}
+
+func TestQuizletExportRoundTrip(t *testing.T) {
+	translations := []domain.Translation{
+		{
+			ID:                    1,
+			OriginalLexicalItem:   "house",
+			OriginalMeaning:       "a building for people to live in",
+			OriginalExamples:      []string{"Our house is old.", "They bought a house."},
+			TranslatedLexicalItem: "дом",
+			TranslatedMeaning:     "здание для жилья",
+			TranslatedExamples:    []string{"Наш дом старый.", "Они купили дом."},
+		},
+		{
+			ID:                    2,
+			OriginalLexicalItem:   "give up",
+			OriginalMeaning:       "to stop trying",
+			OriginalExamples:      []string{"Never give up."},
+			TranslatedLexicalItem: "сдаваться",
+			TranslatedMeaning:     "прекращать попытки",
+			TranslatedExamples:    []string{"Никогда не сдавайся."},
+		},
+	}
+	var cards []domain.CollectionTranslation
+	for _, translation := range translations {
+		cards = append(cards, domain.CollectionTranslation{Template: domain.CardTemplateForward, Translation: translation})
+	}
+	for _, separator := range []string{"", "|"} {
+		var buf bytes.Buffer
+		exporter := export.NewRegistry(nil)[export.ProductQuizlet]
+		err := exporter.Export(context.Background(), &buf, export.Collection{Name: "Words", Cards: cards}, export.Options{Separator: separator})
+		if err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if format := Detect("words.txt", buf.Bytes()); format != FormatQuizlet {
+			t.Fatalf("Detect() = %q, want %q", format, FormatQuizlet)
+		}
+		rows, err := Parse(context.Background(), FormatQuizlet, buf.Bytes(), Options{Separator: separator})
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if len(rows) != len(translations) {
+			t.Fatalf("separator %q: Parse() returned %d rows, want %d", separator, len(rows), len(translations))
+		}
+		for i, row := range rows {
+			want := translations[i]
+			want.ID = 0
+			if !reflect.DeepEqual(row.Translation, want) {
+				t.Errorf("separator %q: row %d = %+v, want %+v", separator, i, row.Translation, want)
+			}
+		}
+	}
+}
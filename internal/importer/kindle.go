@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// KindleFilter selects the lookups of a Kindle vocabulary, the zero value selects all of them
+type KindleFilter struct {
+	// Books are the titles, ASINs or ids of the books, a title matches when it contains the given one ignoring the case
+	Books []string
+	// Since and Until bound the time of the lookups, a zero time doesn't bound
+	Since time.Time
+	Until time.Time
+}
+
+// kindleLookup is a word looked up in a book
+type kindleLookup struct {
+	word, stem, lang, usage string
+	bookID, asin, title     string
+	lookedUpAt              time.Time
+}
+
+// parseKindle reads the words looked up on a Kindle, a word becomes a row with the stem as the lexical item
+// and the sentences it was looked up in as the context of the row, the earliest one first
+func parseKindle(ctx context.Context, data []byte, filter KindleFilter) ([]Row, error) {
+	file, err := os.CreateTemp("", "kindle-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	defer db.Close()
+
+	lookups, err := db.QueryContext(ctx, `
+		SELECT w.id, w.word, COALESCE(w.stem, ''), COALESCE(w.lang, ''), COALESCE(l.usage, ''),
+		       COALESCE(b.id, ''), COALESCE(b.asin, ''), COALESCE(b.title, ''), COALESCE(l.timestamp, w.timestamp, 0)
+		FROM LOOKUPS l
+		JOIN WORDS w ON w.id = l.word_key
+		LEFT JOIN BOOK_INFO b ON b.id = l.book_key
+		ORDER BY COALESCE(l.timestamp, w.timestamp, 0), l.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file is not a Kindle vocabulary: %v", ErrMalformed, err)
+	}
+	defer lookups.Close()
+
+	var rows []Row
+	index := make(map[string]int)
+	for lookups.Next() {
+		var wordID string
+		var lookup kindleLookup
+		var timestamp int64
+		err := lookups.Scan(&wordID, &lookup.word, &lookup.stem, &lookup.lang, &lookup.usage, &lookup.bookID, &lookup.asin, &lookup.title, &timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read lookup: %v", ErrMalformed, err)
+		}
+		lookup.lookedUpAt = time.UnixMilli(timestamp).UTC()
+		if !filter.matches(lookup) {
+			continue
+		}
+		i, ok := index[wordID]
+		if !ok {
+			i = len(rows)
+			index[wordID] = i
+			rows = append(rows, Row{Line: i + 1, Translation: kindleTranslation(lookup)})
+		}
+		if usage := strings.TrimSpace(lookup.usage); usage != "" && !slices.Contains(rows[i].Context, usage) {
+			rows[i].Context = append(rows[i].Context, usage)
+		}
+	}
+	if err := lookups.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read lookups: %v", ErrMalformed, err)
+	}
+	return rows, nil
+}
+
+func kindleTranslation(lookup kindleLookup) domain.Translation {
+	word := strings.TrimSpace(lookup.stem)
+	if word == "" {
+		word = strings.TrimSpace(lookup.word)
+	}
+	t := domain.Translation{OriginalLexicalItem: word}
+	// a vocabulary holds the words of books in different languages, Kindle keeps their codes, e.g. "en" or "en-GB"
+	code, _, _ := strings.Cut(strings.ToLower(lookup.lang), "-")
+	t.TranslatedFrom = domain.LanguageCodes[code]
+	return t
+}
+
+func (f KindleFilter) matches(lookup kindleLookup) bool {
+	if !f.Since.IsZero() && lookup.lookedUpAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !lookup.lookedUpAt.Before(f.Until) {
+		return false
+	}
+	if len(f.Books) == 0 {
+		return true
+	}
+	for _, book := range f.Books {
+		if book == lookup.bookID || strings.EqualFold(book, lookup.asin) ||
+			strings.Contains(strings.ToLower(lookup.title), strings.ToLower(book)) {
+			return true
+		}
+	}
+	return false
+}
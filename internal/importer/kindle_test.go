@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC)
+}
+
+// kindleVocabulary builds a vocab.db with the tables of the Kindle Vocabulary Builder and returns its content
+func kindleVocabulary(t *testing.T) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vocab.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open vocabulary: %v", err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TABLE WORDS (id TEXT PRIMARY KEY, word TEXT, stem TEXT, lang TEXT, category INTEGER DEFAULT 0, timestamp INTEGER DEFAULT 0, profileid TEXT)`,
+		`CREATE TABLE LOOKUPS (id TEXT PRIMARY KEY, word_key TEXT, book_key TEXT, dict_key TEXT, pos TEXT, usage TEXT, timestamp INTEGER DEFAULT 0)`,
+		`CREATE TABLE BOOK_INFO (id TEXT PRIMARY KEY, asin TEXT, guid TEXT, lang TEXT, title TEXT, authors TEXT)`,
+		`INSERT INTO BOOK_INFO (id, asin, title) VALUES ('hobbit-id', 'B007978NPG', 'The Hobbit'), ('dune-id', 'B00B7NPRY8', 'Dune')`,
+		`INSERT INTO WORDS (id, word, stem, lang) VALUES ('en:running', 'running', 'run', 'en'), ('en:spice', 'spice', '', 'en-GB'), ('de:Häuser', 'Häuser', 'Haus', 'de')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to build vocabulary: %v", err)
+		}
+	}
+	lookups := []struct {
+		id, word, book, usage string
+		at                    time.Time
+	}{
+		{"1", "en:running", "hobbit-id", "He was running home.", day(1)},
+		{"2", "en:spice", "dune-id", "The spice must flow.", day(2)},
+		{"3", "en:running", "dune-id", " Running is good. ", day(3)},
+		{"4", "de:Häuser", "hobbit-id", "Die Häuser sind alt.", day(4)},
+		{"5", "en:running", "hobbit-id", "He was running home.", day(5)},
+	}
+	for _, lookup := range lookups {
+		_, err := db.Exec(`INSERT INTO LOOKUPS (id, word_key, book_key, usage, timestamp) VALUES (?, ?, ?, ?, ?)`,
+			lookup.id, lookup.word, lookup.book, lookup.usage, lookup.at.UnixMilli())
+		if err != nil {
+			t.Fatalf("failed to build vocabulary: %v", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close vocabulary: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read vocabulary: %v", err)
+	}
+	return data
+}
+
+func TestParseKindle(t *testing.T) {
+	data := kindleVocabulary(t)
+	if format := Detect("vocab.db", data); format != FormatKindle {
+		t.Fatalf("Detect() = %q, want %q", format, FormatKindle)
+	}
+
+	run := func(line int, context ...string) Row {
+		return Row{Line: line, Translation: domain.Translation{OriginalLexicalItem: "run", TranslatedFrom: "english"}, Context: context}
+	}
+	spice := func(line int) Row {
+		return Row{Line: line, Translation: domain.Translation{OriginalLexicalItem: "spice", TranslatedFrom: "english"}, Context: []string{"The spice must flow."}}
+	}
+	haus := func(line int) Row {
+		return Row{Line: line, Translation: domain.Translation{OriginalLexicalItem: "Haus", TranslatedFrom: "german"}, Context: []string{"Die Häuser sind alt."}}
+	}
+	tests := []struct {
+		name   string
+		filter KindleFilter
+		want   []Row
+	}{
+		{
+			name: "all lookups",
+			want: []Row{run(1, "He was running home.", "Running is good."), spice(2), haus(3)},
+		},
+		{
+			name:   "book by title",
+			filter: KindleFilter{Books: []string{"hobbit"}},
+			want:   []Row{run(1, "He was running home."), haus(2)},
+		},
+		{
+			name:   "book by ASIN",
+			filter: KindleFilter{Books: []string{"b00b7npry8"}},
+			want:   []Row{spice(1), run(2, "Running is good.")},
+		},
+		{
+			name:   "book by id",
+			filter: KindleFilter{Books: []string{"dune-id"}},
+			want:   []Row{spice(1), run(2, "Running is good.")},
+		},
+		{
+			name:   "unknown book",
+			filter: KindleFilter{Books: []string{"Emma"}},
+		},
+		{
+			name:   "since and until",
+			filter: KindleFilter{Since: day(3), Until: day(5)},
+			want:   []Row{run(1, "Running is good."), haus(2)},
+		},
+		{
+			name:   "book and since",
+			filter: KindleFilter{Books: []string{"The Hobbit"}, Since: day(2)},
+			want:   []Row{haus(1), run(2, "He was running home.")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Parse(context.Background(), FormatKindle, data, Options{Kindle: tt.filter})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var byteOrderMark = []byte("\xef\xbb\xbf")
+
+// parseCSV reads RFC 4180 CSV, the delimiter is a comma unless a separator is given
+func parseCSV(data []byte, options Options) ([]Row, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, byteOrderMark)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if options.Separator != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(options.Separator)
+	}
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return parseRecords(records, lines, options.Columns), nil
+}
+
+// parseTSV reads tab separated values, a value can't have tabs or line breaks so nothing is quoted
+func parseTSV(data []byte, options Options) []Row {
+	var records [][]string
+	var lines []int
+	for i, line := range strings.Split(string(bytes.TrimPrefix(data, byteOrderMark)), "\n") {
+		records = append(records, strings.Split(strings.TrimSuffix(line, "\r"), "\t"))
+		lines = append(lines, i+1)
+	}
+	return parseRecords(records, lines, options.Columns)
+}
+
+// parseQuizlet reads the text exported by Quizlet, a term and its definition per line separated with a tab
+// or the given separator. The text of the Quizlet export of this app is read as well
+func parseQuizlet(data []byte, options Options) []Row {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, byteOrderMark)), "\r\n", "\n")
+	if strings.Contains(text, "\noriginalExamples:") {
+		return parseQuizletExport(text, options)
+	}
+	separator := "\t"
+	if options.Separator != "" {
+		separator = options.Separator
+	}
+	var records [][]string
+	var lines []int
+	for i, line := range strings.Split(text, "\n") {
+		term, definition, _ := strings.Cut(line, separator)
+		records = append(records, []string{term, definition})
+		lines = append(lines, i+1)
+	}
+	columns := options.Columns
+	if len(columns) == 0 {
+		columns = []string{ColumnWord, ColumnTranslation}
+	}
+	return parseRecords(records, lines, columns)
+}
+
+var numberedItem = regexp.MustCompile(`^\d+\) `)
+
+// parseQuizletExport reads the cards exported by this app for Quizlet, each card is
+//
+//	word;originalMeaning: meaning
+//	originalExamples:
+//	1) example
+//	translatedLexicalItem: translation
+//	translatedMeaning: meaning
+//	translatedExamples:
+//	1) example
+//
+// and the cards are separated with blank lines
+func parseQuizletExport(text string, options Options) []Row {
+	separator := ";"
+	if options.Separator != "" {
+		separator = options.Separator
+	}
+	var rows []Row
+	var row *Row
+	var examples *[]string
+	for i, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.Contains(line, separator+"originalMeaning: "):
+			rows = append(rows, Row{Line: i + 1})
+			row = &rows[len(rows)-1]
+			word, meaning, _ := strings.Cut(line, separator+"originalMeaning: ")
+			row.Translation.OriginalLexicalItem = strings.TrimSpace(word)
+			row.Translation.OriginalMeaning = strings.TrimSpace(meaning)
+			examples = nil
+		case row == nil:
+		case line == "originalExamples:":
+			examples = &row.Translation.OriginalExamples
+		case line == "translatedExamples:":
+			examples = &row.Translation.TranslatedExamples
+		case strings.HasPrefix(line, "translatedLexicalItem: "):
+			row.Translation.TranslatedLexicalItem = strings.TrimSpace(strings.TrimPrefix(line, "translatedLexicalItem: "))
+			examples = nil
+		case strings.HasPrefix(line, "translatedMeaning: "):
+			row.Translation.TranslatedMeaning = strings.TrimSpace(strings.TrimPrefix(line, "translatedMeaning: "))
+			examples = nil
+		case examples != nil && numberedItem.MatchString(line):
+			*examples = append(*examples, strings.TrimSpace(numberedItem.ReplaceAllString(line, "")))
+		case examples != nil && strings.TrimSpace(line) != "" && len(*examples) > 0:
+			// an example that spans several lines
+			last := &(*examples)[len(*examples)-1]
+			*last += "\n" + strings.TrimSpace(line)
+		}
+	}
+	return rows
+}
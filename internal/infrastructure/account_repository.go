@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+func (t *translationRepository) GetAccountSettings(ctx context.Context, userID int) (domain.AccountSettings, error) {
+	var settings domain.AccountSettings
+	err := t.conn.QueryRow(
+		ctx,
+		"SELECT timezone, day_rollover_hour FROM users WHERE id = $1",
+		userID,
+	).Scan(&settings.Timezone, &settings.DayRolloverHour)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.AccountSettings{}, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return domain.AccountSettings{}, fmt.Errorf("failed to get account settings of user %d: %w", userID, err)
+	}
+	return settings, nil
+}
+
+func (t *translationRepository) UpdateAccountSettings(ctx context.Context, userID int, settings domain.AccountSettingsUpdate) (domain.AccountSettings, error) {
+	var updated domain.AccountSettings
+	err := t.conn.QueryRow(
+		ctx,
+		`UPDATE users
+		 SET timezone = COALESCE($1, timezone),
+			 day_rollover_hour = COALESCE($2, day_rollover_hour)
+		 WHERE id = $3
+		 RETURNING timezone, day_rollover_hour`,
+		settings.Timezone,
+		settings.DayRolloverHour,
+		userID,
+	).Scan(&updated.Timezone, &updated.DayRolloverHour)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.AccountSettings{}, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return domain.AccountSettings{}, fmt.Errorf("failed to update account settings of user %d: %w", userID, err)
+	}
+	return updated, nil
+}
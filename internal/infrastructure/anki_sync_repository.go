@@ -0,0 +1,211 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// SetAnkiConnect stores where the AnkiConnect add-on of the user listens, nil disconnects it
+func (t *translationRepository) SetAnkiConnect(ctx context.Context, userID int, conn *domain.AnkiConnection) error {
+	var url, key *string
+	if conn != nil {
+		url = &conn.URL
+		if conn.Key != "" {
+			key = &conn.Key
+		}
+	}
+	cmdTag, err := t.conn.Exec(ctx, "UPDATE users SET ankiconnect_url = $1, ankiconnect_key = $2 WHERE id = $3", url, key, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set ankiconnect of user %d: %w", userID, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// GetAnkiTarget returns the AnkiConnect add-on and the deck the user's collection is synced to
+func (t *translationRepository) GetAnkiTarget(ctx context.Context, userID, collectionID int) (domain.AnkiTarget, error) {
+	var target domain.AnkiTarget
+	err := t.conn.QueryRow(
+		ctx,
+		`SELECT COALESCE(u.ankiconnect_url, ''), COALESCE(u.ankiconnect_key, ''), COALESCE(c.anki_deck, ''), c.collection_name, c.scheduler
+		 FROM collections c
+		 JOIN users u ON u.id = c.user_id
+		 WHERE c.id = $1 AND c.user_id = $2`,
+		collectionID,
+		userID,
+	).Scan(&target.Connection.URL, &target.Connection.Key, &target.Deck, &target.CollectionName, &target.Scheduler)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.AnkiTarget{}, domain.ErrCollectionNotFound
+	}
+	if err != nil {
+		return domain.AnkiTarget{}, fmt.Errorf("failed to get anki target of collection %d: %w", collectionID, err)
+	}
+	return target, nil
+}
+
+// SetAnkiDeck maps the user's collection to an Anki deck
+func (t *translationRepository) SetAnkiDeck(ctx context.Context, userID, collectionID int, deck string) error {
+	cmdTag, err := t.conn.Exec(
+		ctx,
+		"UPDATE collections SET anki_deck = $1 WHERE id = $2 AND user_id = $3",
+		deck,
+		collectionID,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set anki deck of collection %d: %w", collectionID, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return domain.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// GetAnkiNotes returns the ids of the Anki notes of the collection by the ids of their translations
+func (t *translationRepository) GetAnkiNotes(ctx context.Context, collectionID int) (map[int]int64, error) {
+	rows, err := t.conn.Query(ctx, "SELECT translation_id, anki_note_id FROM anki_notes WHERE collection_id = $1", collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anki notes of collection %d: %w", collectionID, err)
+	}
+	defer rows.Close()
+
+	notes := make(map[int]int64)
+	for rows.Next() {
+		var translationID int
+		var noteID int64
+		if err := rows.Scan(&translationID, &noteID); err != nil {
+			return nil, fmt.Errorf("failed to scan anki note: %w", err)
+		}
+		notes[translationID] = noteID
+	}
+	return notes, rows.Err()
+}
+
+// SaveAnkiNote stores the Anki note of a translation of the collection, replacing the previous one
+func (t *translationRepository) SaveAnkiNote(ctx context.Context, collectionID, translationID int, noteID int64) error {
+	_, err := t.conn.Exec(
+		ctx,
+		`INSERT INTO anki_notes (collection_id, translation_id, anki_note_id)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (collection_id, translation_id) DO UPDATE
+		 SET anki_note_id = EXCLUDED.anki_note_id`,
+		collectionID,
+		translationID,
+		noteID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save anki note of translation %d: %w", translationID, err)
+	}
+	return nil
+}
+
+// GetAnkiCards returns the links of the cards of the collection to their Anki cards
+func (t *translationRepository) GetAnkiCards(ctx context.Context, collectionID int) ([]domain.AnkiCardLink, error) {
+	rows, err := t.conn.Query(
+		ctx,
+		`SELECT ac.collection_translation_id, ac.anki_card_id, ac.pushed_review
+		 FROM anki_cards ac
+		 JOIN collection_translations ct ON ct.id = ac.collection_translation_id
+		 WHERE ct.collection_id = $1`,
+		collectionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anki cards of collection %d: %w", collectionID, err)
+	}
+	defer rows.Close()
+
+	var links []domain.AnkiCardLink
+	for rows.Next() {
+		var link domain.AnkiCardLink
+		if err := rows.Scan(&link.CollectionTranslationID, &link.CardID, &link.PushedReview); err != nil {
+			return nil, fmt.Errorf("failed to scan anki card: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// SaveAnkiCard stores the link of a card to its Anki card, replacing the previous one
+func (t *translationRepository) SaveAnkiCard(ctx context.Context, link domain.AnkiCardLink) error {
+	_, err := t.conn.Exec(
+		ctx,
+		`INSERT INTO anki_cards (collection_translation_id, anki_card_id, pushed_review)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (collection_translation_id) DO UPDATE
+		 SET anki_card_id = EXCLUDED.anki_card_id,
+			 pushed_review = EXCLUDED.pushed_review`,
+		link.CollectionTranslationID,
+		link.CardID,
+		link.PushedReview,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save anki card of card %d: %w", link.CollectionTranslationID, err)
+	}
+	return nil
+}
+
+// SaveSyncedReviews stores the scheduling state of the card after the reviews made in Anki together with their
+// review logs. Unlike SaveReview the siblings aren't buried, Anki did it when the reviews were made
+func (t *translationRepository) SaveSyncedReviews(ctx context.Context, card domain.CollectionTranslation, userID int, reviews []domain.ReviewLog) error {
+	state := card.CardState
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(
+			ctx,
+			`UPDATE collection_translations
+			 SET due = $1, ease = $2, interval_days = $3, reps = $4, lapses = $5, last_review = $6
+			 WHERE id = $7
+			   AND collection_id = (
+				 SELECT id FROM collections
+				 WHERE id = $8 AND user_id = $9
+			   )`,
+			state.Due,
+			state.Ease,
+			state.IntervalDays,
+			state.Reps,
+			state.Lapses,
+			state.LastReview,
+			card.ID,
+			card.Collection.ID,
+			userID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update card state: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return domain.ErrCollectionTranslationNotFound
+		}
+		for _, review := range reviews {
+			_, err = tx.Exec(
+				ctx,
+				`INSERT INTO review_logs (
+					collection_translation_id, user_id, grade, prev_due, next_due, prev_reps, prev_interval_days,
+					interval_days, ease, stability, difficulty, elapsed_days, duration_ms, reviewed_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+				review.CollectionTranslationID,
+				review.UserID,
+				review.Grade,
+				review.PrevDue,
+				review.NextDue,
+				review.PrevReps,
+				review.PrevIntervalDays,
+				review.IntervalDays,
+				review.Ease,
+				review.Stability,
+				review.Difficulty,
+				review.ElapsedDays,
+				review.DurationMs,
+				review.ReviewedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert review log: %w", err)
+			}
+		}
+		return nil
+	})
+}
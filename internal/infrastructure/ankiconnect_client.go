@@ -0,0 +1,252 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/anki"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const ankiConnectVersion = 6
+
+type ankiConnectRequest struct {
+	Action  string `json:"action"`
+	Version int    `json:"version"`
+	Params  any    `json:"params,omitempty"`
+	Key     string `json:"key,omitempty"`
+}
+
+type ankiConnectResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+type ankiConnectNote struct {
+	DeckName  string            `json:"deckName"`
+	ModelName string            `json:"modelName"`
+	Fields    map[string]string `json:"fields"`
+	Tags      []string          `json:"tags"`
+	Options   ankiNoteOptions   `json:"options"`
+}
+
+type ankiNoteOptions struct {
+	AllowDuplicate bool   `json:"allowDuplicate"`
+	DuplicateScope string `json:"duplicateScope"`
+}
+
+type ankiCardTemplate struct {
+	Name  string `json:"Name"`
+	Front string `json:"Front"`
+	Back  string `json:"Back"`
+}
+
+// AnkiConnectClient talks the JSON protocol of the AnkiConnect add-on, every call is made to the add-on of the user.
+// The calls come from the server, so its HTTPClient should use NewPublicTransport: the add-on has to be reachable
+// at a public address, or at an allowed one when the server runs on the same host as Anki
+type AnkiConnectClient struct {
+	httpClient *HTTPClient
+}
+
+// NewAnkiConnectClient creates a new instance of AnkiConnectClient
+func NewAnkiConnectClient(httpClient *HTTPClient) *AnkiConnectClient {
+	return &AnkiConnectClient{httpClient: httpClient}
+}
+
+// CreateDeck creates the deck, an existing deck is left as is
+func (a AnkiConnectClient) CreateDeck(ctx context.Context, conn domain.AnkiConnection, deck string) error {
+	return a.do(ctx, conn, "createDeck", map[string]any{"deck": deck}, nil)
+}
+
+// EnsureNoteType creates the note type of the notes unless the collection already has it
+func (a AnkiConnectClient) EnsureNoteType(ctx context.Context, conn domain.AnkiConnection) error {
+	var names []string
+	if err := a.do(ctx, conn, "modelNames", nil, &names); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == anki.ModelName {
+			return nil
+		}
+	}
+	fields, templates, css := anki.NoteType()
+	cardTemplates := make([]ankiCardTemplate, 0, len(templates))
+	for _, template := range templates {
+		cardTemplates = append(cardTemplates, ankiCardTemplate{Name: template.Name, Front: template.Front, Back: template.Back})
+	}
+	return a.do(ctx, conn, "createModel", map[string]any{
+		"modelName":     anki.ModelName,
+		"inOrderFields": fields,
+		"css":           css,
+		"cardTemplates": cardTemplates,
+	}, nil)
+}
+
+// AddNotes adds the notes and returns their ids, the id of a note Anki refused, e.g. as a duplicate, is 0
+func (a AnkiConnectClient) AddNotes(ctx context.Context, conn domain.AnkiConnection, notes []domain.AnkiNote) ([]int64, error) {
+	params := make([]ankiConnectNote, 0, len(notes))
+	for _, note := range notes {
+		tags := note.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		params = append(params, ankiConnectNote{
+			DeckName:  note.Deck,
+			ModelName: note.Model,
+			Fields:    note.Fields,
+			Tags:      tags,
+			Options:   ankiNoteOptions{DuplicateScope: "deck"},
+		})
+	}
+	var ids []*int64
+	err := a.do(ctx, conn, "addNotes", map[string]any{"notes": params}, &ids)
+	var connectErr *domain.AnkiConnectError
+	// newer versions of the add-on fail the whole call when a note is refused, the added ones are found by FindNotes
+	if errors.As(err, &connectErr) && ids == nil && isDuplicateError(connectErr.Err) {
+		return make([]int64, len(notes)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int64, len(notes))
+	for i, id := range ids {
+		if i < len(result) && id != nil {
+			result[i] = *id
+		}
+	}
+	return result, nil
+}
+
+// FindNote returns the id of the note in the deck of the note that has the same first field, 0 when there is none
+func (a AnkiConnectClient) FindNote(ctx context.Context, conn domain.AnkiConnection, note domain.AnkiNote) (int64, error) {
+	fields, _, _ := anki.NoteType()
+	query := fmt.Sprintf("%s %s %s", searchTerm("deck", note.Deck), searchTerm("note", note.Model), searchTerm(fields[0], note.Fields[fields[0]]))
+	var ids []int64
+	if err := a.do(ctx, conn, "findNotes", map[string]any{"query": query}, &ids); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// FindCards returns the cards of the notes
+func (a AnkiConnectClient) FindCards(ctx context.Context, conn domain.AnkiConnection, noteIDs []int64) ([]domain.AnkiCardInfo, error) {
+	if len(noteIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(noteIDs))
+	for _, id := range noteIDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	var cardIDs []int64
+	if err := a.do(ctx, conn, "findCards", map[string]any{"query": "nid:" + strings.Join(ids, ",")}, &cardIDs); err != nil {
+		return nil, err
+	}
+	return a.CardsInfo(ctx, conn, cardIDs)
+}
+
+// CardsInfo returns the state of the cards, the cards that no longer exist are left out
+func (a AnkiConnectClient) CardsInfo(ctx context.Context, conn domain.AnkiConnection, cardIDs []int64) ([]domain.AnkiCardInfo, error) {
+	if len(cardIDs) == 0 {
+		return nil, nil
+	}
+	var infos []domain.AnkiCardInfo
+	if err := a.do(ctx, conn, "cardsInfo", map[string]any{"cards": cardIDs}, &infos); err != nil {
+		return nil, err
+	}
+	cards := make([]domain.AnkiCardInfo, 0, len(infos))
+	for _, info := range infos {
+		// a deleted card comes back as an empty object
+		if info.CardID != 0 {
+			cards = append(cards, info)
+		}
+	}
+	return cards, nil
+}
+
+// GetReviews returns the review log of the cards by their ids, oldest first
+func (a AnkiConnectClient) GetReviews(ctx context.Context, conn domain.AnkiConnection, cardIDs []int64) (map[int64][]domain.AnkiReview, error) {
+	if len(cardIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(cardIDs))
+	for _, id := range cardIDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	var reviews map[string][]domain.AnkiReview
+	if err := a.do(ctx, conn, "getReviewsOfCards", map[string]any{"cards": ids}, &reviews); err != nil {
+		return nil, err
+	}
+	result := make(map[int64][]domain.AnkiReview, len(reviews))
+	for id, log := range reviews {
+		cardID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, &domain.AnkiConnectError{Action: "getReviewsOfCards", Err: fmt.Errorf("invalid card id %q", id)}
+		}
+		result[cardID] = log
+	}
+	return result, nil
+}
+
+// Reschedule makes the card due in days and sets its ease, an ease of 0 is left as is
+func (a AnkiConnectClient) Reschedule(ctx context.Context, conn domain.AnkiConnection, cardID int64, days int, ease float64) error {
+	params := map[string]any{"cards": []int64{cardID}, "days": strconv.Itoa(days) + "!"}
+	if err := a.do(ctx, conn, "setDueDate", params, nil); err != nil {
+		return err
+	}
+	if ease <= 0 {
+		return nil
+	}
+	params = map[string]any{"cards": []int64{cardID}, "easeFactors": []int{int(ease * 1000)}}
+	return a.do(ctx, conn, "setEaseFactors", params, nil)
+}
+
+// do calls the action and decodes its result into result when it isn't nil
+func (a AnkiConnectClient) do(ctx context.Context, conn domain.AnkiConnection, action string, params, result any) error {
+	jsonData, err := json.Marshal(ankiConnectRequest{Action: action, Version: ankiConnectVersion, Params: params, Key: conn.Key})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, conn.URL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return &domain.AnkiConnectError{Action: action, Err: err}
+	}
+	defer resp.Body.Close()
+	var reply ankiConnectResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return &domain.AnkiConnectError{Action: action, Err: fmt.Errorf("failed to decode response body: %w", err)}
+	}
+	if result != nil && len(reply.Result) > 0 {
+		if err := json.Unmarshal(reply.Result, result); err != nil {
+			return &domain.AnkiConnectError{Action: action, Err: fmt.Errorf("failed to decode result: %w", err)}
+		}
+	}
+	if reply.Error != nil {
+		return &domain.AnkiConnectError{Action: action, Err: errors.New(*reply.Error)}
+	}
+	return nil
+}
+
+func isDuplicateError(err error) bool {
+	return strings.Contains(err.Error(), "duplicate")
+}
+
+// searchTerm is a term of an Anki search matching the value of the field exactly
+func searchTerm(field, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `*`, `\*`, `_`, `\_`, `:`, `\:`).Replace(value)
+	return fmt.Sprintf(`"%s:%s"`, strings.ToLower(field), value)
+}
@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	defaultAnthropicURL       = "https://api.anthropic.com/v1/messages"
+	defaultAnthropicModel     = "claude-3-5-haiku-latest"
+	anthropicVersion          = "2023-06-01"
+	anthropicMaxTokens        = 1024
+	anthropicContentTypeText  = "text"
+	anthropicContentTypeTool  = "tool_use"
+	anthropicStopReasonLength = "max_tokens"
+)
+
+// AnthropicClient talks to the Anthropic Messages API
+type AnthropicClient struct {
+	httpClient *HTTPClient
+	url        string
+	apiKey     string
+	model      string
+}
+
+// NewAnthropicClient creates a new instance of AnthropicClient, empty url and model fall back to the defaults
+func NewAnthropicClient(httpClient *HTTPClient, url, apiKey, model string) *AnthropicClient {
+	if url == "" {
+		url = defaultAnthropicURL
+	}
+	if model == "" {
+		model = defaultAnthropicModel
+	}
+	return &AnthropicClient{httpClient: httpClient, url: url, apiKey: apiKey, model: model}
+}
+
+// Complete sends the request to the Messages API and returns the concatenated text blocks of the reply.
+// A schema is enforced by forcing the model to call a tool with that input schema, the tool input is returned then
+func (a AnthropicClient) Complete(ctx context.Context, llmReq domain.LLMRequest) (string, error) {
+	messagesReq := domain.AnthropicRequest{
+		Model:     a.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    llmReq.System,
+	}
+	for _, m := range llmReq.Messages {
+		messagesReq.Messages = append(messagesReq.Messages, domain.AnthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	if llmReq.Schema != nil {
+		messagesReq.Tools = []domain.AnthropicTool{{
+			Name:        llmReq.SchemaName,
+			Description: "Records the reply in the required format",
+			InputSchema: llmReq.Schema,
+		}}
+		messagesReq.ToolChoice = &domain.AnthropicToolChoice{Type: "tool", Name: llmReq.SchemaName}
+	}
+	requestBody, err := json.Marshal(messagesReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var messagesResp domain.AnthropicResponse
+	err = json.Unmarshal(body, &messagesResp)
+	if err != nil {
+		return "", fmt.Errorf("could not unmarshall response: %w", err)
+	}
+	if messagesResp.StopReason == anthropicStopReasonLength {
+		return "", fmt.Errorf("response was cut at %d tokens", anthropicMaxTokens)
+	}
+	var content strings.Builder
+	for _, block := range messagesResp.Content {
+		if block.Type == anthropicContentTypeTool && block.Name == llmReq.SchemaName {
+			return string(block.Input), nil
+		}
+		if block.Type == anthropicContentTypeText {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return "", fmt.Errorf("response has no text content")
+	}
+	return content.String(), nil
+}
@@ -0,0 +1,309 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	// maxErrorBodySize limits how much of a failed response is kept in domain.UpstreamError
+	maxErrorBodySize = 4096
+	// defaultBaseBackoff is the first wait when the config leaves BaseBackoff unset
+	defaultBaseBackoff = 100 * time.Millisecond
+)
+
+type HTTPClientConfig struct {
+	// Timeout is the deadline of a single attempt, the request context may only make it shorter
+	Timeout time.Duration
+	// TotalTimeout bounds all the attempts and the waits between them together
+	TotalTimeout time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	// MaxBackoff is the longest wait between attempts, a longer Retry-After fails the request right away
+	MaxBackoff time.Duration
+	// FailureThreshold consecutive failures open the circuit of a host for OpenDuration
+	FailureThreshold int
+	OpenDuration     time.Duration
+	// Transport sends the attempts, nil uses http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// DefaultHTTPClientConfig is tuned for LLM calls, which may take tens of seconds
+func DefaultHTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		Timeout:          60 * time.Second,
+		TotalTimeout:     90 * time.Second,
+		MaxAttempts:      3,
+		BaseBackoff:      500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// HTTPClient is the shared client for outbound calls to third party APIs.
+// It retries 429 and 5xx responses with exponential backoff honoring Retry-After
+// and keeps a circuit breaker per host, so a provider that is down fails fast
+type HTTPClient struct {
+	client *http.Client
+	config HTTPClientConfig
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewHTTPClient creates a new instance of HTTPClient
+func NewHTTPClient(config HTTPClientConfig) *HTTPClient {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaultBaseBackoff
+	}
+	return &HTTPClient{
+		client:   &http.Client{Transport: config.Transport},
+		config:   config,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// Do sends the request and returns the first 2xx response, a non 2xx response is returned as *domain.UpstreamError.
+// The body of the returned response must be closed, closing it releases the attempt deadline
+func (h *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	breaker := h.breaker(req.URL.Host)
+	var deadline time.Time
+	if h.config.TotalTimeout > 0 {
+		deadline = time.Now().Add(h.config.TotalTimeout)
+	}
+	var lastErr error
+	for attempt := 1; attempt <= h.config.MaxAttempts; attempt++ {
+		allowed, probe := breaker.allow()
+		if !allowed {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w: %v", domain.ErrCircuitOpen, lastErr)
+			}
+			return nil, domain.ErrCircuitOpen
+		}
+		resp, err := h.do(req, deadline)
+		if err == nil {
+			breaker.success()
+			return resp, nil
+		}
+		lastErr = err
+
+		if req.Context().Err() != nil {
+			// the caller gave up, it says nothing about the upstream
+			if probe {
+				breaker.release()
+			}
+			break
+		}
+		var upstreamErr *domain.UpstreamError
+		isUpstreamErr := errors.As(err, &upstreamErr)
+		if isUpstreamErr && upstreamErr.StatusCode < http.StatusInternalServerError {
+			// the provider is up and answering, only 5xx and transport errors count as failures
+			breaker.success()
+		} else {
+			breaker.failure(probe)
+		}
+		if !retryable(err) || attempt == h.config.MaxAttempts {
+			break
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// the body has been consumed and can't be sent again
+			break
+		}
+		wait := h.backoff(attempt)
+		if isUpstreamErr && upstreamErr.RetryAfter > wait {
+			if upstreamErr.RetryAfter > h.config.MaxBackoff {
+				// the caller gets the 429 with its Retry-After rather than hanging on
+				break
+			}
+			wait = upstreamErr.RetryAfter
+		}
+		if !deadline.IsZero() && time.Until(deadline) < wait {
+			break
+		}
+		if ctxDeadline, ok := req.Context().Deadline(); ok && time.Until(ctxDeadline) < wait {
+			break
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// do makes a single attempt, it ends at the attempt timeout or at the deadline of all the attempts when that is sooner
+func (h *HTTPClient) do(req *http.Request, deadline time.Time) (*http.Response, error) {
+	attemptDeadline := time.Now().Add(h.config.Timeout)
+	if !deadline.IsZero() && deadline.Before(attemptDeadline) {
+		attemptDeadline = deadline
+	}
+	ctx, cancel := context.WithDeadline(req.Context(), attemptDeadline)
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+	resp, err := h.client.Do(attemptReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer cancel()
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, &domain.UpstreamError{
+			Host:       req.URL.Host,
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (h *HTTPClient) backoff(attempt int) time.Duration {
+	wait := h.config.BaseBackoff << (attempt - 1)
+	if wait > h.config.MaxBackoff || wait <= 0 {
+		wait = h.config.MaxBackoff
+	}
+	// jitter between a half and the full backoff, so clients don't retry in lockstep
+	half := int64(wait / 2)
+	if half <= 0 {
+		return wait
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func (h *HTTPClient) breaker(host string) *circuitBreaker {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b, ok := h.breakers[host]
+	if !ok {
+		b = &circuitBreaker{threshold: h.config.FailureThreshold, openDuration: h.config.OpenDuration}
+		h.breakers[host] = b
+	}
+	return b
+}
+
+func retryable(err error) bool {
+	var upstreamErr *domain.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.StatusCode == http.StatusTooManyRequests || upstreamErr.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, domain.ErrAddressNotAllowed) {
+		return false
+	}
+	// transport errors and attempt timeouts
+	return true
+}
+
+// parseRetryAfter supports both delay-seconds and HTTP-date values
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// circuitBreaker opens after threshold consecutive failures, once openDuration has passed
+// a single probe request is let through and its outcome closes or reopens the circuit
+type circuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow tells whether a request may be sent and whether it is the probe of a half open circuit
+func (b *circuitBreaker) allow() (allowed bool, probe bool) {
+	if b.threshold <= 0 {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// release gives up the probe without an outcome, only the request that took the probe may release it
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// failure counts a failed request, a failed probe reopens the circuit
+func (b *circuitBreaker) failure(probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if probe {
+		b.probing = false
+	}
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.openDuration)
+	}
+}
@@ -0,0 +1,241 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// scriptedServer replies with the given statuses in order and repeats the last one, every reply carries the headers
+func scriptedServer(t *testing.T, headers map[string]string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1))
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(statuses[min(call, len(statuses))-1])
+		_, _ = io.WriteString(w, "reply")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func testHTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+}
+
+func get(t *testing.T, client *HTTPClient, ctx context.Context, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := client.Do(req)
+	if resp != nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		statuses   []int
+		calls      int32
+		wantStatus int // the status of the returned domain.UpstreamError, 0 is a success
+		minElapsed time.Duration
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, calls: 1},
+		{name: "5xx succeeds on retry", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, calls: 3},
+		{name: "retries exhausted", statuses: []int{http.StatusInternalServerError}, calls: 3, wantStatus: http.StatusInternalServerError},
+		{name: "4xx is not retried", statuses: []int{http.StatusBadRequest}, calls: 1, wantStatus: http.StatusBadRequest},
+		{
+			name:       "429 waits for Retry-After",
+			headers:    map[string]string{"Retry-After": "1"},
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			calls:      2,
+			minElapsed: time.Second,
+		},
+		{
+			name:       "429 with Retry-After over MaxBackoff fails right away",
+			headers:    map[string]string{"Retry-After": "60"},
+			statuses:   []int{http.StatusTooManyRequests},
+			calls:      1,
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := scriptedServer(t, tt.headers, tt.statuses...)
+			client := NewHTTPClient(testHTTPClientConfig())
+
+			start := time.Now()
+			_, err := get(t, client, context.Background(), server.URL)
+			elapsed := time.Since(start)
+
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("server called %d times, want %d", got, tt.calls)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("Do() returned after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Do() error = %v", err)
+				}
+				return
+			}
+			var upstreamErr *domain.UpstreamError
+			if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != tt.wantStatus {
+				t.Fatalf("Do() error = %v, want upstream status %d", err, tt.wantStatus)
+			}
+			if upstreamErr.Body != "reply" {
+				t.Errorf("UpstreamError.Body = %q, want reply", upstreamErr.Body)
+			}
+		})
+	}
+}
+
+func TestHTTPClientStopsBackoff(t *testing.T) {
+	server, calls := scriptedServer(t, nil, http.StatusServiceUnavailable)
+
+	t.Run("total timeout", func(t *testing.T) {
+		calls.Store(0)
+		config := testHTTPClientConfig()
+		config.BaseBackoff = 5 * time.Second
+		config.MaxBackoff = 5 * time.Second
+		config.TotalTimeout = 200 * time.Millisecond
+		client := NewHTTPClient(config)
+
+		start := time.Now()
+		_, err := get(t, client, context.Background(), server.URL)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Do() returned after %v, the backoff should not outlive TotalTimeout", elapsed)
+		}
+		if err == nil || calls.Load() != 1 {
+			t.Errorf("Do() error = %v after %d calls, want the 503 of the single attempt", err, calls.Load())
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		calls.Store(0)
+		config := testHTTPClientConfig()
+		config.BaseBackoff = 5 * time.Second
+		config.MaxBackoff = 5 * time.Second
+		client := NewHTTPClient(config)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := get(t, client, ctx, server.URL)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Do() returned after %v, the backoff should stop when the context is canceled", elapsed)
+		}
+		if err == nil || calls.Load() != 1 {
+			t.Errorf("Do() error = %v after %d calls, want the 503 of the single attempt", err, calls.Load())
+		}
+	})
+}
+
+func TestHTTPClientCircuitBreaker(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+		block   = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		<-block
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	config := testHTTPClientConfig()
+	config.MaxAttempts = 1
+	config.FailureThreshold = 2
+	config.OpenDuration = 100 * time.Millisecond
+	client := NewHTTPClient(config)
+
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, client, context.Background(), server.URL); err == nil {
+			t.Fatalf("request %d succeeded against a failing server", i)
+		}
+	}
+	if _, err := get(t, client, context.Background(), server.URL); !errors.Is(err, domain.ErrCircuitOpen) {
+		t.Fatalf("Do() error = %v, want ErrCircuitOpen once the threshold is reached", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("server called %d times, the open circuit should not reach it", got)
+	}
+
+	// once OpenDuration has passed a single probe goes through while the others still fail fast
+	time.Sleep(config.OpenDuration)
+	healthy.Store(true)
+	const requests = 5
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = get(t, client, context.Background(), server.URL)
+		}(i)
+	}
+	// the probe is held by the server until the rest have been turned away
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	var probes, rejected int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			probes++
+		case errors.Is(err, domain.ErrCircuitOpen):
+			rejected++
+		default:
+			t.Errorf("Do() error = %v, want success or ErrCircuitOpen", err)
+		}
+	}
+	if probes != 1 || rejected != requests-1 || calls.Load() != 3 {
+		t.Errorf("half open circuit let %d probes through and rejected %d, server called %d times", probes, rejected, calls.Load())
+	}
+
+	// the successful probe closes the circuit
+	if _, err := get(t, client, context.Background(), server.URL); err != nil {
+		t.Errorf("Do() error = %v after the circuit closed", err)
+	}
+}
+
+func TestHTTPClientDefaultBaseBackoff(t *testing.T) {
+	client := NewHTTPClient(HTTPClientConfig{MaxBackoff: 10 * time.Second})
+	for attempt := 1; attempt <= 3; attempt++ {
+		if wait := client.backoff(attempt); wait > defaultBaseBackoff<<(attempt-1) {
+			t.Errorf("backoff(%d) = %v, want at most %v", attempt, wait, defaultBaseBackoff<<(attempt-1))
+		}
+	}
+}
@@ -2,76 +2,121 @@ package infrastructure
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
 )
 
-type CardRequest struct {
+const defaultMochiURL = "https://app.mochi.cards/api"
+
+type mochiCardRequest struct {
 	Content string `json:"content"`
 	DeckID  string `json:"deck-id"`
 	// TODO: ADD images support later
 }
 
-// MochiCardRepository implements the CardRepository interface
-type MochiCardRepository struct {
-	BaseURL string
-	Token   string
+type mochiDeckRequest struct {
+	Name string `json:"name"`
+}
+
+type mochiResponse struct {
+	ID string `json:"id"`
+}
+
+// MochiClient manages the decks and the cards of Mochi accounts, every call is made with the API token of the account
+type MochiClient struct {
+	httpClient *HTTPClient
+	baseURL    string
 }
 
-// NewMochiCardRepository creates a new instance of MochiCardRepository with the provided configuration
-func NewMochiCardRepository(baseURL, token string) *MochiCardRepository {
-	return &MochiCardRepository{
-		BaseURL: baseURL,
-		Token:   token,
+// NewMochiClient creates a new instance of MochiClient, empty baseURL falls back to the Mochi API
+func NewMochiClient(httpClient *HTTPClient, baseURL string) *MochiClient {
+	if baseURL == "" {
+		baseURL = defaultMochiURL
 	}
+	return &MochiClient{httpClient: httpClient, baseURL: baseURL}
 }
 
-// CreateCard sends a request to create a card using the provided data
-func (m MochiCardRepository) CreateCard(deckID, content string) error {
-	url := m.BaseURL + "/server/cards/"
-	headers := map[string]string{
-		"Content-Type":  "application/json",
-		"Authorization": m.generateBasicAuthHeader(),
+// CreateDeck creates a deck and returns its id
+func (m MochiClient) CreateDeck(ctx context.Context, token, name string) (string, error) {
+	var deck mochiResponse
+	if err := m.do(ctx, token, http.MethodPost, "/decks/", mochiDeckRequest{Name: name}, &deck, false); err != nil {
+		return "", fmt.Errorf("failed to create mochi deck: %w", err)
 	}
-	req := CardRequest{Content: content, DeckID: deckID}
+	return deck.ID, nil
+}
 
-	requestBody, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %v", err)
+// CreateCard creates a card with the Markdown content in the deck and returns its id
+func (m MochiClient) CreateCard(ctx context.Context, token, deckID, content string) (string, error) {
+	var card mochiResponse
+	if err := m.do(ctx, token, http.MethodPost, "/cards/", mochiCardRequest{Content: content, DeckID: deckID}, &card, false); err != nil {
+		return "", fmt.Errorf("failed to create mochi card: %w", err)
 	}
+	return card.ID, nil
+}
 
-	request, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
+// UpdateCard replaces the content of the card and moves it to the deck
+func (m MochiClient) UpdateCard(ctx context.Context, token, cardID, deckID, content string) error {
+	if err := m.do(ctx, token, http.MethodPost, "/cards/"+url.PathEscape(cardID), mochiCardRequest{Content: content, DeckID: deckID}, nil, true); err != nil {
+		return fmt.Errorf("failed to update mochi card %s: %w", cardID, err)
 	}
-	for key, value := range headers {
-		request.Header.Set(key, value)
+	return nil
+}
+
+// DeleteCard deletes the card
+func (m MochiClient) DeleteCard(ctx context.Context, token, cardID string) error {
+	if err := m.do(ctx, token, http.MethodDelete, "/cards/"+url.PathEscape(cardID), nil, nil, true); err != nil {
+		return fmt.Errorf("failed to delete mochi card %s: %w", cardID, err)
 	}
+	return nil
+}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+// do sends the JSON payload and decodes the reply into result when it isn't nil. A request that creates
+// something is sent once, when its reply is lost a retry would create a duplicate
+func (m MochiClient) do(ctx context.Context, token, method, path string, payload, result any, retry bool) error {
+	var body io.Reader
+	var jsonData []byte
+	if payload != nil {
+		var err error
+		jsonData, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.baseURL+path, body)
 	if err != nil {
-		return fmt.Errorf("failed to perform HTTP request: %v", err)
+		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	defer response.Body.Close()
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if !retry {
+			// HTTPClient doesn't send a body it can't get again
+			req.GetBody = nil
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader(token))
 
-	responseBody, err := ioutil.ReadAll(response.Body)
+	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return err
 	}
-
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d, response body: %s", response.StatusCode, responseBody)
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response body: %w", err)
 	}
-
 	return nil
 }
 
-// generateBasicAuthHeader generates the Basic Auth header using the token
-func (m MochiCardRepository) generateBasicAuthHeader() string {
-	auth := m.Token + ":"
-	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
+// basicAuthHeader is the Basic Auth header with the token as the user name, the way Mochi expects it
+func basicAuthHeader(token string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(token+":"))
 }
@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mochiRequest struct {
+	method, path, auth string
+	body               mochiCardRequest
+}
+
+// newMochiServer records the requests and replies with status, or with the id of a created card
+func newMochiServer(t *testing.T, status int) (*MochiClient, *[]mochiRequest) {
+	t.Helper()
+	var requests []mochiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := mochiRequest{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization")}
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&request.body)
+		}
+		requests = append(requests, request)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(mochiResponse{ID: "card1"})
+	}))
+	t.Cleanup(server.Close)
+	httpClient := NewHTTPClient(HTTPClientConfig{
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+	return NewMochiClient(httpClient, server.URL), &requests
+}
+
+func TestMochiClientCreateCard(t *testing.T) {
+	client, requests := newMochiServer(t, http.StatusOK)
+	id, err := client.CreateCard(context.Background(), "token", "deck1", "# word")
+	if err != nil {
+		t.Fatalf("CreateCard: %v", err)
+	}
+	if id != "card1" {
+		t.Errorf("id = %q, want card1", id)
+	}
+	want := mochiRequest{
+		method: http.MethodPost,
+		path:   "/cards/",
+		auth:   basicAuthHeader("token"),
+		body:   mochiCardRequest{Content: "# word", DeckID: "deck1"},
+	}
+	if len(*requests) != 1 || (*requests)[0] != want {
+		t.Errorf("requests = %+v, want %+v", *requests, want)
+	}
+}
+
+func TestMochiClientCreateCardIsNotRetried(t *testing.T) {
+	client, requests := newMochiServer(t, http.StatusBadGateway)
+	if _, err := client.CreateCard(context.Background(), "token", "deck1", "# word"); err == nil {
+		t.Fatal("CreateCard succeeded, want an error")
+	}
+	if len(*requests) != 1 {
+		t.Errorf("%d requests were sent, want 1", len(*requests))
+	}
+}
+
+func TestMochiClientUpdateCard(t *testing.T) {
+	client, requests := newMochiServer(t, http.StatusOK)
+	if err := client.UpdateCard(context.Background(), "token", "card/1", "deck1", "# words"); err != nil {
+		t.Fatalf("UpdateCard: %v", err)
+	}
+	want := mochiRequest{
+		method: http.MethodPost,
+		path:   "/cards/card/1",
+		auth:   basicAuthHeader("token"),
+		body:   mochiCardRequest{Content: "# words", DeckID: "deck1"},
+	}
+	if len(*requests) != 1 || (*requests)[0] != want {
+		t.Errorf("requests = %+v, want %+v", *requests, want)
+	}
+}
+
+func TestMochiClientUpdateCardIsRetried(t *testing.T) {
+	client, requests := newMochiServer(t, http.StatusBadGateway)
+	if err := client.UpdateCard(context.Background(), "token", "card1", "deck1", "# words"); err == nil {
+		t.Fatal("UpdateCard succeeded, want an error")
+	}
+	if len(*requests) != 3 {
+		t.Errorf("%d requests were sent, want 3", len(*requests))
+	}
+	for _, request := range *requests {
+		if request.body.Content != "# words" {
+			t.Errorf("content = %q, want the same body on every attempt", request.body.Content)
+		}
+	}
+}
+
+func TestMochiClientDeleteCard(t *testing.T) {
+	client, requests := newMochiServer(t, http.StatusOK)
+	if err := client.DeleteCard(context.Background(), "token", "card1"); err != nil {
+		t.Fatalf("DeleteCard: %v", err)
+	}
+	want := mochiRequest{method: http.MethodDelete, path: "/cards/card1", auth: basicAuthHeader("token")}
+	if len(*requests) != 1 || (*requests)[0] != want {
+		t.Errorf("requests = %+v, want %+v", *requests, want)
+	}
+}
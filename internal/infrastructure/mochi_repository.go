@@ -0,0 +1,125 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// SetMochiToken stores the Mochi API token of the user, nil disconnects Mochi
+func (t *translationRepository) SetMochiToken(ctx context.Context, userID int, token *string) error {
+	cmdTag, err := t.conn.Exec(ctx, "UPDATE users SET mochi_api_token = $1 WHERE id = $2", token, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set mochi api token of user %d: %w", userID, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// SetMochiDeck maps the user's collection to a Mochi deck
+func (t *translationRepository) SetMochiDeck(ctx context.Context, userID, collectionID int, deckID string) error {
+	cmdTag, err := t.conn.Exec(
+		ctx,
+		"UPDATE collections SET mochi_deck_id = $1 WHERE id = $2 AND user_id = $3",
+		deckID,
+		collectionID,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set mochi deck of collection %d: %w", collectionID, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return domain.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// GetMochiTarget returns the Mochi account and deck the user's collection is synced to
+func (t *translationRepository) GetMochiTarget(ctx context.Context, userID, collectionID int) (domain.MochiTarget, error) {
+	var target domain.MochiTarget
+	err := t.conn.QueryRow(
+		ctx,
+		`SELECT COALESCE(u.mochi_api_token, ''), COALESCE(c.mochi_deck_id, ''), c.collection_name
+		 FROM collections c
+		 JOIN users u ON u.id = c.user_id
+		 WHERE c.id = $1 AND c.user_id = $2`,
+		collectionID,
+		userID,
+	).Scan(&target.APIToken, &target.DeckID, &target.CollectionName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.MochiTarget{}, domain.ErrCollectionNotFound
+	}
+	if err != nil {
+		return domain.MochiTarget{}, fmt.Errorf("failed to get mochi target of collection %d: %w", collectionID, err)
+	}
+	return target, nil
+}
+
+// GetMochiCards returns the Mochi cards of the collection
+func (t *translationRepository) GetMochiCards(ctx context.Context, collectionID int) ([]domain.MochiCard, error) {
+	rows, err := t.conn.Query(
+		ctx,
+		`SELECT collection_id, translation_id, mochi_card_id, mochi_deck_id, content_hash, synced_at
+		 FROM mochi_cards
+		 WHERE collection_id = $1`,
+		collectionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mochi cards of collection %d: %w", collectionID, err)
+	}
+	defer rows.Close()
+
+	var cards []domain.MochiCard
+	for rows.Next() {
+		var card domain.MochiCard
+		err := rows.Scan(&card.CollectionID, &card.TranslationID, &card.CardID, &card.DeckID, &card.ContentHash, &card.SyncedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mochi card: %w", err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// SaveMochiCard stores the Mochi card of a translation of the collection, replacing the previous one
+func (t *translationRepository) SaveMochiCard(ctx context.Context, card domain.MochiCard) error {
+	_, err := t.conn.Exec(
+		ctx,
+		`INSERT INTO mochi_cards (collection_id, translation_id, mochi_card_id, mochi_deck_id, content_hash, synced_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (collection_id, translation_id) DO UPDATE
+		 SET mochi_card_id = EXCLUDED.mochi_card_id,
+			 mochi_deck_id = EXCLUDED.mochi_deck_id,
+			 content_hash = EXCLUDED.content_hash,
+			 synced_at = EXCLUDED.synced_at`,
+		card.CollectionID,
+		card.TranslationID,
+		card.CardID,
+		card.DeckID,
+		card.ContentHash,
+		card.SyncedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save mochi card of translation %d: %w", card.TranslationID, err)
+	}
+	return nil
+}
+
+// DeleteMochiCard forgets the Mochi card of a translation of the collection
+func (t *translationRepository) DeleteMochiCard(ctx context.Context, collectionID, translationID int) error {
+	_, err := t.conn.Exec(
+		ctx,
+		"DELETE FROM mochi_cards WHERE collection_id = $1 AND translation_id = $2",
+		collectionID,
+		translationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete mochi card of translation %d: %w", translationID, err)
+	}
+	return nil
+}
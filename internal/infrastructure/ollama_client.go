@@ -0,0 +1,84 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	defaultOllamaURL   = "http://localhost:11434/api/chat"
+	defaultOllamaModel = "llama3.1"
+)
+
+// OllamaClient talks to a local Ollama server through its native chat API.
+// llama.cpp and other servers exposing the OpenAI API can be used through OpenAIClient instead
+type OllamaClient struct {
+	httpClient *HTTPClient
+	url        string
+	apiKey     string
+	model      string
+}
+
+// NewOllamaClient creates a new instance of OllamaClient, empty url and model fall back to the defaults
+func NewOllamaClient(httpClient *HTTPClient, url, apiKey, model string) *OllamaClient {
+	if url == "" {
+		url = defaultOllamaURL
+	}
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	return &OllamaClient{httpClient: httpClient, url: url, apiKey: apiKey, model: model}
+}
+
+// Complete sends a non-streaming chat request and returns the content of the reply
+func (o OllamaClient) Complete(ctx context.Context, llmReq domain.LLMRequest) (string, error) {
+	chatReq := domain.OllamaChatRequest{Model: o.model, Format: "json"}
+	if llmReq.Schema != nil {
+		chatReq.Format = llmReq.Schema
+	}
+	if llmReq.System != "" {
+		chatReq.Messages = append(chatReq.Messages, domain.OllamaMessage{Role: "system", Content: llmReq.System})
+	}
+	for _, m := range llmReq.Messages {
+		chatReq.Messages = append(chatReq.Messages, domain.OllamaMessage{Role: m.Role, Content: m.Content})
+	}
+	requestBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// plain Ollama has no auth, but it is often hidden behind a proxy that has
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var chatResp domain.OllamaChatResponse
+	err = json.Unmarshal(body, &chatResp)
+	if err != nil {
+		return "", fmt.Errorf("could not unmarshall response: %w", err)
+	}
+	if chatResp.Message.Content == "" {
+		return "", fmt.Errorf("response has no content")
+	}
+	return chatResp.Message.Content, nil
+}
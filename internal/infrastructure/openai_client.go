@@ -0,0 +1,91 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	defaultOpenAIURL = "https://api.openai.com/v1/chat/completions"
+	// the oldest model family that supports JSON schema structured outputs
+	defaultOpenAIModel = "gpt-4o-mini"
+)
+
+// OpenAIClient talks to any endpoint compatible with the OpenAI chat completions API,
+// e.g. OpenAI itself, Azure OpenAI, OpenRouter or a llama.cpp server
+type OpenAIClient struct {
+	httpClient *HTTPClient
+	url        string
+	apiKey     string
+	model      string
+}
+
+// NewOpenAIClient creates a new instance of OpenAIClient, empty url and model fall back to the OpenAI defaults
+func NewOpenAIClient(httpClient *HTTPClient, url, apiKey, model string) *OpenAIClient {
+	if url == "" {
+		url = defaultOpenAIURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAIClient{httpClient: httpClient, url: url, apiKey: apiKey, model: model}
+}
+
+// Complete sends the request to the chat completions endpoint and returns the content of the only choice
+func (o OpenAIClient) Complete(ctx context.Context, llmReq domain.LLMRequest) (string, error) {
+	chatReq := domain.ChatGPTRequest{Model: o.model}
+	if llmReq.System != "" {
+		chatReq.Messages = append(chatReq.Messages, domain.ChatGPTMessage{Role: "system", Content: llmReq.System})
+	}
+	for _, m := range llmReq.Messages {
+		chatReq.Messages = append(chatReq.Messages, domain.ChatGPTMessage{Role: m.Role, Content: m.Content})
+	}
+	if llmReq.Schema != nil {
+		chatReq.ResponseFormat = &domain.ChatGPTResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &domain.ChatGPTJSONSchema{
+				Name:   llmReq.SchemaName,
+				Schema: llmReq.Schema,
+				Strict: true,
+			},
+		}
+	}
+	requestBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var chatGPTResp domain.ChatGPTResponse
+	err = json.Unmarshal(body, &chatGPTResp)
+	if err != nil {
+		return "", fmt.Errorf("could not unmarshall response: %w", err)
+	}
+	if len(chatGPTResp.Choices) != 1 {
+		return "", fmt.Errorf("expected number of choices is 1, actual %d", len(chatGPTResp.Choices))
+	}
+	return chatGPTResp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	defaultPaplaURL = "https://api.papla.media/v1"
+	paplaModelID    = "papla_p1"
+
+	paplaVoiceEnglish = "77bbc6a8-9660-4e8a-92f1-e021f4f80cc9"
+	paplaVoicePolish  = "346e8979-02bd-4cc9-9e15-7929da7c6ac1"
+	paplaVoiceRussian = "acdbd9ef-a79b-429f-b8ed-78a80c61d459"
+)
+
+// PaplaTTSClient converts text to speech with the Papla Media API
+type PaplaTTSClient struct {
+	httpClient *HTTPClient
+	baseURL    string
+	apiKey     string
+}
+
+// NewPaplaTTSClient creates a new instance of PaplaTTSClient, empty baseURL falls back to the Papla API
+func NewPaplaTTSClient(httpClient *HTTPClient, baseURL, apiKey string) *PaplaTTSClient {
+	if baseURL == "" {
+		baseURL = defaultPaplaURL
+	}
+	return &PaplaTTSClient{httpClient: httpClient, baseURL: baseURL, apiKey: apiKey}
+}
+
+// Stream returns the mp3 audio of text pronounced in language, the caller has to close it
+func (p PaplaTTSClient) Stream(ctx context.Context, text, language string) (io.ReadCloser, error) {
+	ttsPayload := map[string]string{
+		"text":     text,
+		"model_id": paplaModelID,
+	}
+	jsonData, err := json.Marshal(ttsPayload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	var voice string
+	if language == domain.LanguagePolish {
+		voice = paplaVoicePolish
+	} else if language == domain.LanguageRussian {
+		voice = paplaVoiceRussian
+	} else {
+		voice = paplaVoiceEnglish
+	}
+	ttsEndpoint := fmt.Sprintf("%s/text-to-speech/%s/stream", p.baseURL, voice)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ttsEndpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("papla-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// sharedAddressSpace is the carrier grade NAT range of RFC 6598
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewPublicTransport creates a transport for URLs given by users, it only connects to public addresses
+// and to the allowed ones. The resolved address is checked when dialing, so neither a host name pointing
+// at a private address nor a redirect gets around it. Proxies from the environment aren't used
+func NewPublicTransport(allowed []netip.Prefix) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", domain.ErrAddressNotAllowed, address)
+			}
+			if !isPublicAddress(addrPort.Addr().Unmap(), allowed) {
+				return fmt.Errorf("%w: %s", domain.ErrAddressNotAllowed, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// ParseAllowedAddresses parses a comma separated list of IP addresses and CIDR ranges
+func ParseAllowedAddresses(list string) ([]netip.Prefix, error) {
+	var allowed []netip.Prefix
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", value, err)
+			}
+			allowed = append(allowed, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address range %q: %w", value, err)
+		}
+		allowed = append(allowed, prefix.Masked())
+	}
+	return allowed, nil
+}
+
+// isPublicAddress rejects loopback, private, link local (the cloud metadata endpoints among them),
+// shared, multicast and unspecified addresses unless they are allowed
+func isPublicAddress(addr netip.Addr, allowed []netip.Prefix) bool {
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() &&
+		!addr.IsUnspecified() && !sharedAddressSpace.Contains(addr)
+}
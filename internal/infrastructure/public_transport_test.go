@@ -0,0 +1,84 @@
+package infrastructure
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicAddress(netip.MustParseAddr(tt.addr), nil); got != tt.want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestParseAllowedAddresses(t *testing.T) {
+	allowed, err := ParseAllowedAddresses(" 127.0.0.1, 192.168.0.0/16,")
+	if err != nil {
+		t.Fatalf("ParseAllowedAddresses: %v", err)
+	}
+	for addr, want := range map[string]bool{"127.0.0.1": true, "192.168.5.5": true, "127.0.0.2": false, "10.0.0.1": false} {
+		if got := isPublicAddress(netip.MustParseAddr(addr), allowed); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if _, err := ParseAllowedAddresses("localhost"); err == nil {
+		t.Error("ParseAllowedAddresses(localhost) succeeded, want an error")
+	}
+}
+
+func TestPublicTransport(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	get := func(allowed []netip.Prefix) error {
+		client := NewHTTPClient(HTTPClientConfig{Timeout: time.Second, MaxAttempts: 3, Transport: NewPublicTransport(allowed)})
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	if err := get(nil); !errors.Is(err, domain.ErrAddressNotAllowed) {
+		t.Errorf("loopback request error = %v, want %v", err, domain.ErrAddressNotAllowed)
+	}
+	if err := get([]netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}); err != nil {
+		t.Errorf("allowed loopback request: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("%d requests reached the server, want 1", requests)
+	}
+}
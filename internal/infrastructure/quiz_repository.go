@@ -0,0 +1,34 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveQuizResults records the answers of a quiz, either all of them or none
+func (t *translationRepository) SaveQuizResults(ctx context.Context, results []domain.QuizResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		for _, result := range results {
+			_, err := tx.Exec(
+				ctx,
+				`INSERT INTO quiz_results (collection_translation_id, user_id, correct, duration_ms, answered_at)
+				 VALUES ($1, $2, $3, $4, $5)`,
+				result.CollectionTranslationID,
+				result.UserID,
+				result.Correct,
+				result.DurationMs,
+				result.AnsweredAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert quiz result: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,248 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveReview stores the scheduling state computed for the card after a review together with its review log,
+// the tags and the suspension are stored too as a review can turn the card into a leech.
+// The sibling cards of the same translation are buried until siblingsBuriedUntil.
+// The card is only updated while it is in the state the review was computed from, domain.ErrReviewConflict
+// is returned when it has been reviewed in the meantime
+func (t *translationRepository) SaveReview(
+	ctx context.Context,
+	card domain.CollectionTranslation,
+	review domain.ReviewLog,
+	siblingsBuriedUntil time.Time,
+) error {
+	state := card.CardState
+	tags := card.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(
+			ctx,
+			`UPDATE collection_translations
+			 SET due = $1, ease = $2, interval_days = $3, reps = $4, lapses = $5, stability = $6, difficulty = $7, last_review = $8,
+				 tags = $12, suspended = $13
+			 WHERE id = $9
+			   AND collection_id = (
+				 SELECT id FROM collections
+				 WHERE id = $10 AND user_id = $11
+			   )
+			   AND reps = $14 AND due IS NOT DISTINCT FROM $15`,
+			state.Due,
+			state.Ease,
+			state.IntervalDays,
+			state.Reps,
+			state.Lapses,
+			state.Stability,
+			state.Difficulty,
+			state.LastReview,
+			review.CollectionTranslationID,
+			card.Collection.ID,
+			review.UserID,
+			tags,
+			card.Suspended,
+			review.PrevReps,
+			review.PrevDue,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update card state: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			var exists bool
+			err = tx.QueryRow(
+				ctx,
+				`SELECT EXISTS (
+					SELECT 1 FROM collection_translations ct
+					JOIN collections c ON ct.collection_id = c.id
+					WHERE ct.id = $1 AND c.id = $2 AND c.user_id = $3
+				)`,
+				review.CollectionTranslationID,
+				card.Collection.ID,
+				review.UserID,
+			).Scan(&exists)
+			if err != nil {
+				return fmt.Errorf("failed to check card: %w", err)
+			}
+			if exists {
+				return domain.ErrReviewConflict
+			}
+			return domain.ErrCollectionTranslationNotFound
+		}
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO review_logs (
+				collection_translation_id, user_id, grade, prev_due, next_due, prev_reps, prev_interval_days,
+				interval_days, ease, stability, difficulty, elapsed_days, duration_ms, reviewed_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			review.CollectionTranslationID,
+			review.UserID,
+			review.Grade,
+			review.PrevDue,
+			review.NextDue,
+			review.PrevReps,
+			review.PrevIntervalDays,
+			review.IntervalDays,
+			review.Ease,
+			review.Stability,
+			review.Difficulty,
+			review.ElapsedDays,
+			review.DurationMs,
+			review.ReviewedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert review log: %w", err)
+		}
+		_, err = tx.Exec(
+			ctx,
+			`UPDATE collection_translations
+			 SET buried_until = GREATEST(buried_until, $1)
+			 WHERE collection_id = $2 AND translation_id = $3 AND id <> $4`,
+			siblingsBuriedUntil,
+			card.Collection.ID,
+			card.Translation.ID,
+			card.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bury sibling cards: %w", err)
+		}
+		_, err = tx.Exec(
+			ctx,
+			`UPDATE review_session_cards
+			 SET done = TRUE
+			 WHERE NOT done
+			 AND collection_translation_id IN (
+				SELECT id FROM collection_translations
+				WHERE collection_id = $1 AND translation_id = $2 AND id <> $3
+			 )`,
+			card.Collection.ID,
+			card.Translation.ID,
+			card.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to take sibling cards out of review sessions: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetReviewLogs returns the review history of the user's card, oldest first
+func (t *translationRepository) GetReviewLogs(ctx context.Context, collectionTranslationID int, userID int) ([]domain.ReviewLog, error) {
+	rows, err := t.conn.Query(
+		ctx,
+		`SELECT id, collection_translation_id, user_id, grade, prev_due, next_due, prev_reps, prev_interval_days,
+			interval_days, ease, stability, difficulty, elapsed_days, duration_ms, reviewed_at
+		 FROM review_logs
+		 WHERE collection_translation_id = $1 AND user_id = $2
+		 ORDER BY reviewed_at, id`,
+		collectionTranslationID,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve review logs for collection_translation_id %d: %w", collectionTranslationID, err)
+	}
+	defer rows.Close()
+
+	var logs []domain.ReviewLog
+	for rows.Next() {
+		var log domain.ReviewLog
+		if err := rows.Scan(
+			&log.ID,
+			&log.CollectionTranslationID,
+			&log.UserID,
+			&log.Grade,
+			&log.PrevDue,
+			&log.NextDue,
+			&log.PrevReps,
+			&log.PrevIntervalDays,
+			&log.IntervalDays,
+			&log.Ease,
+			&log.Stability,
+			&log.Difficulty,
+			&log.ElapsedDays,
+			&log.DurationMs,
+			&log.ReviewedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan review_logs row: %w", err)
+		}
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
+
+// SetCardSuspended suspends or unsuspends the card, a suspended card is taken out of the open review sessions
+func (t *translationRepository) SetCardSuspended(ctx context.Context, collectionTranslationID, collectionID, userID int, suspended bool) error {
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(
+			ctx,
+			`UPDATE collection_translations
+			 SET suspended = $1, template_suspended = FALSE
+			 WHERE id = $2
+			   AND collection_id = (
+				 SELECT id FROM collections
+				 WHERE id = $3 AND user_id = $4
+			   )`,
+			suspended,
+			collectionTranslationID,
+			collectionID,
+			userID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update card suspension: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return domain.ErrCollectionTranslationNotFound
+		}
+		if suspended {
+			return finishSessionCard(ctx, tx, collectionTranslationID)
+		}
+		return nil
+	})
+}
+
+// BuryCard hides the card from the reviews until the time, it is taken out of the open review sessions
+func (t *translationRepository) BuryCard(ctx context.Context, collectionTranslationID, collectionID, userID int, until time.Time) error {
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(
+			ctx,
+			`UPDATE collection_translations
+			 SET buried_until = $1
+			 WHERE id = $2
+			   AND collection_id = (
+				 SELECT id FROM collections
+				 WHERE id = $3 AND user_id = $4
+			   )`,
+			until,
+			collectionTranslationID,
+			collectionID,
+			userID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bury card: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return domain.ErrCollectionTranslationNotFound
+		}
+		return finishSessionCard(ctx, tx, collectionTranslationID)
+	})
+}
+
+func finishSessionCard(ctx context.Context, tx pgx.Tx, collectionTranslationID int) error {
+	_, err := tx.Exec(
+		ctx,
+		"UPDATE review_session_cards SET done = TRUE WHERE collection_translation_id = $1 AND NOT done",
+		collectionTranslationID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to take card out of review sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateReviewSession queues the due cards of the user's collections within their daily limits,
+// overdue cards first, then new ones up to newLimit
+func (t *translationRepository) CreateReviewSession(
+	ctx context.Context,
+	userID int,
+	collectionIDs []int,
+	newLimit int,
+	now time.Time,
+	dayStart time.Time,
+) (int, error) {
+	var sessionID int
+	err := pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		var owned int
+		err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM collections WHERE user_id = $1 AND id = ANY($2)", userID, collectionIDs).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("failed to check collections: %w", err)
+		}
+		if owned != len(collectionIDs) {
+			return domain.ErrCollectionNotFound
+		}
+		err = tx.QueryRow(
+			ctx,
+			"INSERT INTO review_sessions (user_id, collection_ids, created_at) VALUES ($1, $2, $3) RETURNING id",
+			userID,
+			collectionIDs,
+			now,
+		).Scan(&sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to create review session: %w", err)
+		}
+		_, err = tx.Exec(
+			ctx,
+			"WITH "+dailyDueQueue+`
+			INSERT INTO review_session_cards (session_id, collection_translation_id, position, is_new)
+			SELECT $4, id, ROW_NUMBER() OVER (ORDER BY is_new, due, id), is_new
+			FROM (
+				SELECT q.*, ROW_NUMBER() OVER (PARTITION BY q.is_new ORDER BY q.due, q.id) AS rank
+				FROM due_queue q
+			) queue
+			WHERE NOT is_new OR rank <= $5`,
+			collectionIDs,
+			dayStart,
+			now,
+			sessionID,
+			newLimit,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue session cards: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sessionID, nil
+}
+
+// GetReviewSession returns the user's session with the counts of cards left in its queue
+func (t *translationRepository) GetReviewSession(ctx context.Context, sessionID int, userID int) (*domain.ReviewSession, error) {
+	var session domain.ReviewSession
+	err := t.conn.QueryRow(
+		ctx,
+		`SELECT s.id, s.collection_ids, s.created_at,
+			COUNT(sc.collection_translation_id) FILTER (WHERE NOT sc.done AND sc.is_new),
+			COUNT(sc.collection_translation_id) FILTER (WHERE NOT sc.done AND NOT sc.is_new)
+		 FROM review_sessions s
+		 LEFT JOIN review_session_cards sc ON sc.session_id = s.id
+		 WHERE s.id = $1 AND s.user_id = $2
+		 GROUP BY s.id`,
+		sessionID,
+		userID,
+	).Scan(&session.ID, &session.CollectionIDs, &session.CreatedAt, &session.Remaining.New, &session.Remaining.Review)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrReviewSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review session %d: %w", sessionID, err)
+	}
+	session.Remaining.Total = session.Remaining.New + session.Remaining.Review
+	return &session, nil
+}
+
+// GetReviewSessionBatch returns the next cards of the session queue
+func (t *translationRepository) GetReviewSessionBatch(ctx context.Context, sessionID int, limit int) ([]domain.CollectionTranslation, error) {
+	query := "SELECT " + collectionTranslationColumns + collectionTranslationJoins + `
+		JOIN review_session_cards sc ON sc.collection_translation_id = ct.id
+		WHERE sc.session_id = $1 AND NOT sc.done
+		ORDER BY sc.position
+		LIMIT $2
+	`
+	cards, err := t.queryCollectionTranslations(ctx, query, sessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cards of review session %d: %w", sessionID, err)
+	}
+	return cards, nil
+}
+
+func (t *translationRepository) GetReviewSessionCard(ctx context.Context, sessionID int, collectionTranslationID int) (*domain.ReviewSessionCard, error) {
+	var card domain.ReviewSessionCard
+	err := t.conn.QueryRow(
+		ctx,
+		`SELECT sc.collection_translation_id, ct.collection_id, sc.done, sc.last_answered_at
+		 FROM review_session_cards sc
+		 JOIN collection_translations ct ON sc.collection_translation_id = ct.id
+		 WHERE sc.session_id = $1 AND sc.collection_translation_id = $2`,
+		sessionID,
+		collectionTranslationID,
+	).Scan(&card.CollectionTranslationID, &card.CollectionID, &card.Done, &card.LastAnsweredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCollectionTranslationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review session card: %w", err)
+	}
+	return &card, nil
+}
+
+// MarkReviewSessionCard records an answer in the session queue, a card that has to be
+// seen again is moved to the end of the queue instead of being done
+func (t *translationRepository) MarkReviewSessionCard(ctx context.Context, sessionID int, collectionTranslationID int, answeredAt time.Time, again bool) error {
+	_, err := t.conn.Exec(
+		ctx,
+		`UPDATE review_session_cards
+		 SET last_answered_at = $3,
+			 done = NOT $4,
+			 position = CASE WHEN $4
+				THEN (SELECT MAX(position) + 1 FROM review_session_cards WHERE session_id = $1)
+				ELSE position END
+		 WHERE session_id = $1 AND collection_translation_id = $2`,
+		sessionID,
+		collectionTranslationID,
+		answeredAt,
+		again,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark review session card: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// searchHeadlineOptions keep the whole field and wrap the matched words in the markers of domain.HighlightHTML
+const searchHeadlineOptions = `StartSel="` + domain.SearchMatchStart + `", StopSel="` + domain.SearchMatchStop + `", HighlightAll=true`
+
+// SearchTranslations finds the translations saved to the user's collections whose lexical items, meanings or examples
+// match the query. The query is parsed with the text search configurations of both languages of a translation,
+// and the lexical items and the meanings also match when they are close to the query, so typos are tolerated.
+// The best matches go first
+func (t *translationRepository) SearchTranslations(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, error) {
+	rows, err := t.conn.Query(
+		ctx,
+		`WITH owned AS (
+			SELECT translation_id,
+				   array_agg(collection_id ORDER BY collection_id) AS collection_ids,
+				   array_agg(collection_name ORDER BY collection_id) AS collection_names
+			FROM (
+				SELECT DISTINCT ct.translation_id, c.id AS collection_id, c.collection_name
+				FROM collection_translations ct
+				JOIN collections c ON c.id = ct.collection_id
+				WHERE c.user_id = $1
+			) saved
+			GROUP BY translation_id
+		), found AS (
+			SELECT t.id, t.lexical_item, t.meaning, t.examples, t.translated_from, t.translated_to,
+				   t.translated_lexical_item, t.translated_meaning, t.translated_examples, t.part_of_speech,
+				   o.collection_ids, o.collection_names, q.query,
+				   ts_rank(t.search_vector, q.query) + GREATEST(
+					   word_similarity($2, t.lexical_item),
+					   word_similarity($2, t.meaning),
+					   word_similarity($2, t.translated_lexical_item),
+					   word_similarity($2, t.translated_meaning)
+				   ) AS rank
+			FROM owned o
+			JOIN translations t ON t.id = o.translation_id
+			CROSS JOIN LATERAL (
+				SELECT websearch_to_tsquery(search_config(t.translated_from), $2) ||
+					   websearch_to_tsquery(search_config(t.translated_to), $2) AS query
+			) q
+			WHERE t.search_vector @@ q.query
+			   OR $2 <% t.lexical_item
+			   OR $2 <% t.meaning
+			   OR $2 <% t.translated_lexical_item
+			   OR $2 <% t.translated_meaning
+			ORDER BY rank DESC, t.id
+			LIMIT $4
+		)
+		SELECT f.id, f.lexical_item, f.meaning, f.examples, f.translated_from, f.translated_to,
+			   f.translated_lexical_item, f.translated_meaning, f.translated_examples, f.part_of_speech,
+			   f.collection_ids, f.collection_names, f.rank,
+			   ts_headline(search_config(f.translated_from), f.lexical_item, f.query, $3),
+			   ts_headline(search_config(f.translated_from), f.meaning, f.query, $3),
+			   ARRAY(
+				   SELECT ts_headline(search_config(f.translated_from), e.example, f.query, $3)
+				   FROM unnest(f.examples) WITH ORDINALITY AS e(example, n)
+				   ORDER BY e.n
+			   ),
+			   ts_headline(search_config(f.translated_to), f.translated_lexical_item, f.query, $3),
+			   ts_headline(search_config(f.translated_to), f.translated_meaning, f.query, $3),
+			   ARRAY(
+				   SELECT ts_headline(search_config(f.translated_to), e.example, f.query, $3)
+				   FROM unnest(f.translated_examples) WITH ORDINALITY AS e(example, n)
+				   ORDER BY e.n
+			   )
+		FROM found f
+		ORDER BY f.rank DESC, f.id`,
+		q.UserID,
+		q.Query,
+		searchHeadlineOptions,
+		q.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search translations of user %d: %w", q.UserID, err)
+	}
+	defer rows.Close()
+
+	results := []domain.SearchResult{}
+	for rows.Next() {
+		var result domain.SearchResult
+		var collectionIDs []int
+		var collectionNames []string
+		tr := &result.Translation
+		h := &result.Highlights
+		err := rows.Scan(
+			&tr.ID,
+			&tr.OriginalLexicalItem,
+			&tr.OriginalMeaning,
+			&tr.OriginalExamples,
+			&tr.TranslatedFrom,
+			&tr.TranslatedTo,
+			&tr.TranslatedLexicalItem,
+			&tr.TranslatedMeaning,
+			&tr.TranslatedExamples,
+			&tr.PartOfSpeech,
+			&collectionIDs,
+			&collectionNames,
+			&result.Rank,
+			&h.OriginalLexicalItem,
+			&h.OriginalMeaning,
+			&h.OriginalExamples,
+			&h.TranslatedLexicalItem,
+			&h.TranslatedMeaning,
+			&h.TranslatedExamples,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		for i, id := range collectionIDs {
+			result.Collections = append(result.Collections, domain.SearchCollection{ID: id, Name: collectionNames[i]})
+		}
+		h.OriginalLexicalItem = domain.HighlightHTML(h.OriginalLexicalItem)
+		h.OriginalMeaning = domain.HighlightHTML(h.OriginalMeaning)
+		h.TranslatedLexicalItem = domain.HighlightHTML(h.TranslatedLexicalItem)
+		h.TranslatedMeaning = domain.HighlightHTML(h.TranslatedMeaning)
+		for i := range h.OriginalExamples {
+			h.OriginalExamples[i] = domain.HighlightHTML(h.OriginalExamples[i])
+		}
+		for i := range h.TranslatedExamples {
+			h.TranslatedExamples[i] = domain.HighlightHTML(h.TranslatedExamples[i])
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,129 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// localDate converts a UTC timestamp column into the study day it belongs to, in the time zone passed as $3
+// with days rolling over at the hour passed as $4
+const localDate = "(((%s AT TIME ZONE 'UTC') AT TIME ZONE $3) - make_interval(hours => $4::int))::date"
+
+// statsCollections keeps the cards of the requested collection, or of all the unarchived collections of the user
+const statsCollections = "c.user_id = $1 AND (c.id = $2 OR ($2 = 0 AND NOT c.archived))"
+
+// GetCardCounts counts the cards of the user by their learning state and due date, suspended cards are left out
+// and new cards are counted apart from the due ones
+func (t *translationRepository) GetCardCounts(ctx context.Context, q domain.StatsQuery) (domain.CardCounts, error) {
+	endOfToday := q.DayStart().AddDate(0, 0, 1).UTC()
+	endOfTomorrow := q.DayStart().AddDate(0, 0, 2).UTC()
+	var counts domain.CardCounts
+	err := t.conn.QueryRow(
+		ctx,
+		`SELECT
+			COUNT(*) FILTER (WHERE ct.last_review IS NOT NULL AND ct.due < $3
+				AND (ct.buried_until IS NULL OR ct.buried_until <= $6)),
+			COUNT(*) FILTER (WHERE ct.last_review IS NOT NULL AND ct.due >= $3 AND ct.due < $4),
+			COUNT(*) FILTER (WHERE ct.last_review IS NULL),
+			COUNT(*) FILTER (WHERE ct.last_review IS NOT NULL AND ct.interval_days < $5),
+			COUNT(*) FILTER (WHERE ct.last_review IS NOT NULL AND ct.interval_days >= $5)
+		 FROM collection_translations ct
+		 JOIN collections c ON ct.collection_id = c.id
+		 WHERE `+statsCollections+`
+		 AND NOT ct.suspended`,
+		q.UserID,
+		q.CollectionID,
+		endOfToday,
+		endOfTomorrow,
+		domain.MatureIntervalDays,
+		q.Now.UTC(),
+	).Scan(&counts.DueToday, &counts.DueTomorrow, &counts.New, &counts.Learning, &counts.Mature)
+	if err != nil {
+		return domain.CardCounts{}, fmt.Errorf("failed to count cards: %w", err)
+	}
+	return counts, nil
+}
+
+// GetReviewsPerDay returns the number of reviews for every study day the user has reviewed on
+func (t *translationRepository) GetReviewsPerDay(ctx context.Context, q domain.StatsQuery) ([]domain.DayCount, error) {
+	return t.queryDayCounts(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), COUNT(*)
+		FROM (
+			SELECT `+fmt.Sprintf(localDate, "rl.reviewed_at")+` AS day
+			FROM review_logs rl
+			JOIN collection_translations ct ON rl.collection_translation_id = ct.id
+			WHERE rl.user_id = $1 AND ($2 = 0 OR ct.collection_id = $2)
+		) reviews
+		GROUP BY day
+		ORDER BY day`,
+		q.UserID,
+		q.CollectionID,
+		q.Location.String(),
+		q.DayRolloverHour,
+	)
+}
+
+// GetDueForecast returns the number of learned cards due on every study day up to the end of the forecast,
+// new and suspended cards are not due
+func (t *translationRepository) GetDueForecast(ctx context.Context, q domain.StatsQuery) ([]domain.DayCount, error) {
+	return t.queryDayCounts(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), COUNT(*)
+		FROM (
+			SELECT `+fmt.Sprintf(localDate, "ct.due")+` AS day
+			FROM collection_translations ct
+			JOIN collections c ON ct.collection_id = c.id
+			WHERE `+statsCollections+`
+			AND ct.last_review IS NOT NULL AND NOT ct.suspended
+			AND ct.due < $5
+		) due_cards
+		GROUP BY day
+		ORDER BY day`,
+		q.UserID,
+		q.CollectionID,
+		q.Location.String(),
+		q.DayRolloverHour,
+		q.DayStart().AddDate(0, 0, domain.ForecastDays).UTC(),
+	)
+}
+
+// GetRetention counts the passed and all reviews of already learned cards over the last q.Days
+func (t *translationRepository) GetRetention(ctx context.Context, q domain.StatsQuery) (passed int, total int, err error) {
+	since := q.DayStart().AddDate(0, 0, -q.Days+1).UTC()
+	err = t.conn.QueryRow(
+		ctx,
+		`SELECT COUNT(*) FILTER (WHERE rl.grade > $4), COUNT(*)
+		 FROM review_logs rl
+		 JOIN collection_translations ct ON rl.collection_translation_id = ct.id
+		 WHERE rl.user_id = $1 AND ($2 = 0 OR ct.collection_id = $2)
+		 AND rl.reviewed_at >= $3
+		 AND rl.prev_reps > 0`,
+		q.UserID,
+		q.CollectionID,
+		since,
+		domain.GradeAgain,
+	).Scan(&passed, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compute retention: %w", err)
+	}
+	return passed, total, nil
+}
+
+func (t *translationRepository) queryDayCounts(ctx context.Context, query string, args ...any) ([]domain.DayCount, error) {
+	rows, err := t.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count by day: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []domain.DayCount
+	for rows.Next() {
+		var count domain.DayCount
+		if err := rows.Scan(&count.Date, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan day count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
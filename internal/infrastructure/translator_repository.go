@@ -2,11 +2,14 @@ package infrastructure
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -23,8 +26,8 @@ func NewTranslationRepository(conn *pgxpool.Pool) *translationRepository {
 func (t *translationRepository) AddTranslation(ctx context.Context, translation domain.Translation, translatedFrom, translatedTo string) (int, error) {
 	var id int
 	err := t.conn.QueryRow(ctx, `
-		INSERT INTO translations(lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO translations(lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples, part_of_speech)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`,
 		translation.OriginalLexicalItem,
@@ -35,6 +38,7 @@ func (t *translationRepository) AddTranslation(ctx context.Context, translation
 		translation.TranslatedLexicalItem,
 		translation.TranslatedMeaning,
 		translation.TranslatedExamples,
+		translation.PartOfSpeech,
 	).Scan(&id)
 
 	if err != nil {
@@ -43,12 +47,57 @@ func (t *translationRepository) AddTranslation(ctx context.Context, translation
 	return id, nil
 }
 
+// UpdateTranslation replaces the looked up translation with a fresh one, the cards saved with it show the fresh one
+func (t *translationRepository) UpdateTranslation(ctx context.Context, translationID int, translation domain.Translation) error {
+	_, err := t.conn.Exec(ctx, `
+		UPDATE translations
+		SET meaning = $2, examples = $3, translated_lexical_item = $4, translated_meaning = $5, translated_examples = $6, part_of_speech = $7
+		WHERE id = $1
+	`,
+		translationID,
+		translation.OriginalMeaning,
+		translation.OriginalExamples,
+		translation.TranslatedLexicalItem,
+		translation.TranslatedMeaning,
+		translation.TranslatedExamples,
+		translation.PartOfSpeech,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update translation %d: %w", translationID, err)
+	}
+	return nil
+}
+
+// AddImportedTranslation inserts a translation read from a user's file, it is kept out of the lookups by GetTranslation
+func (t *translationRepository) AddImportedTranslation(ctx context.Context, translation domain.Translation) (int, error) {
+	var id int
+	err := t.conn.QueryRow(ctx, `
+		INSERT INTO translations(lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples, part_of_speech, imported)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, true)
+		RETURNING id
+	`,
+		translation.OriginalLexicalItem,
+		translation.OriginalMeaning,
+		translation.OriginalExamples,
+		translation.TranslatedFrom,
+		translation.TranslatedTo,
+		translation.TranslatedLexicalItem,
+		translation.TranslatedMeaning,
+		translation.TranslatedExamples,
+		translation.PartOfSpeech,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert imported translation: %w", err)
+	}
+	return id, nil
+}
+
 // GetAllTranslations retrieves all translations from the database
 func (t *translationRepository) GetAllTranslations(ctx context.Context) ([]domain.Translation, error) {
 	var translations []domain.Translation
 
 	rows, err := t.conn.Query(ctx, `
-		SELECT lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples
+		SELECT lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples, part_of_speech
 		FROM translations
 	`)
 	if err != nil {
@@ -67,6 +116,7 @@ func (t *translationRepository) GetAllTranslations(ctx context.Context) ([]domai
 			&translation.TranslatedLexicalItem,
 			&translation.TranslatedMeaning,
 			&translation.TranslatedExamples,
+			&translation.PartOfSpeech,
 		)
 		if err != nil {
 			return nil, err
@@ -80,10 +130,12 @@ func (t *translationRepository) GetAllTranslations(ctx context.Context) ([]domai
 // GetTranslation retrieves a translation based on the lexical item and the languages
 func (t *translationRepository) GetTranslation(ctx context.Context, lexicalItem, translateFrom, translateTo string) (*domain.Translation, error) {
 	lexicalItem = strings.ToLower(lexicalItem)
+	// a forced refresh updates the row in place, older duplicate rows lose to the newest one
 	rows, err := t.conn.Query(ctx, `
-		SELECT lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples
+		SELECT id, lexical_item, meaning, examples, translated_from, translated_to, translated_lexical_item, translated_meaning, translated_examples, part_of_speech
 		FROM translations
-		WHERE lexical_item = $1 AND translated_from = $2 AND translated_to = $3
+		WHERE lower(lexical_item) = $1 AND translated_from = $2 AND translated_to = $3 AND NOT imported
+		ORDER BY id DESC
 		LIMIT 1;
 	`, lexicalItem, translateFrom, translateTo)
 	if err != nil {
@@ -94,6 +146,7 @@ func (t *translationRepository) GetTranslation(ctx context.Context, lexicalItem,
 	for rows.Next() {
 		var translation domain.Translation
 		err := rows.Scan(
+			&translation.ID,
 			&translation.OriginalLexicalItem,
 			&translation.OriginalMeaning,
 			&translation.OriginalExamples,
@@ -102,6 +155,7 @@ func (t *translationRepository) GetTranslation(ctx context.Context, lexicalItem,
 			&translation.TranslatedLexicalItem,
 			&translation.TranslatedMeaning,
 			&translation.TranslatedExamples,
+			&translation.PartOfSpeech,
 		)
 		if err != nil {
 			return nil, err
@@ -112,49 +166,101 @@ func (t *translationRepository) GetTranslation(ctx context.Context, lexicalItem,
 	return nil, nil
 }
 
-func (t *translationRepository) CreateCollection(ctx context.Context, userID int, collectionName string) (int, error) {
-	_, err := t.conn.Exec(
-		ctx,
-		"INSERT INTO collections (user_id, collection_name) VALUES ($1, $2)",
-		userID,
-		collectionName,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create a collection: %w", err)
-	}
-	return 0, nil
-}
-
+// collectionColumns selects a collection with the counts of its cards at $1
+const collectionColumns = `
+	SELECT c.id, c.collection_name, c.user_id, c.scheduler, c.new_cards_per_day, c.reviews_per_day, c.leech_threshold, c.leech_action,
+		   c.card_templates, c.description, COALESCE(c.default_translated_from, ''), COALESCE(c.default_translated_to, ''),
+		   COALESCE(c.color, ''), COALESCE(c.icon, ''), c.archived, n.words, n.due, n.new
+	FROM collections c
+	CROSS JOIN LATERAL (
+		SELECT
+			COUNT(DISTINCT ct.translation_id) AS words,
+			COUNT(*) FILTER (WHERE ct.last_review IS NOT NULL AND ct.due <= $1 AND NOT ct.suspended
+				AND (ct.buried_until IS NULL OR ct.buried_until <= $1)) AS due,
+			COUNT(*) FILTER (WHERE ct.last_review IS NULL AND NOT ct.suspended
+				AND (ct.buried_until IS NULL OR ct.buried_until <= $1)) AS new
+		FROM collection_translations ct
+		WHERE ct.collection_id = c.id
+	) n
+`
+
+// GetCollectionsByUserID lists the user's collections with the counts of their words and cards
 func (t *translationRepository) GetCollectionsByUserID(ctx context.Context, userID int) ([]domain.Collection, error) {
 	var collections []domain.Collection
 
-	query := "SELECT id, collection_name, user_id FROM collections WHERE user_id = $1"
+	query := collectionColumns + `
+		WHERE c.user_id = $2
+		ORDER BY c.id
+	`
 
-	rows, err := t.conn.Query(ctx, query, userID)
+	rows, err := t.conn.Query(ctx, query, time.Now().UTC(), userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve collections for user_id %d: %w", userID, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var collection domain.Collection
-		if err := rows.Scan(&collection.ID, &collection.Name, &collection.UserID); err != nil {
-			return nil, fmt.Errorf("failed to scan collection row: %w", err)
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
 		}
 		collections = append(collections, collection)
 	}
 
-	return collections, nil
+	return collections, rows.Err()
+}
+
+// GetCollectionByID returns the user's collection with the counts of its cards
+func (t *translationRepository) GetCollectionByID(ctx context.Context, collectionID int, userID int) (*domain.Collection, error) {
+	query := collectionColumns + "WHERE c.id = $2 AND c.user_id = $3"
+	collection, err := scanCollection(t.conn.QueryRow(ctx, query, time.Now().UTC(), collectionID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func scanCollection(row pgx.Row) (domain.Collection, error) {
+	var collection domain.Collection
+	var counts domain.CollectionCounts
+	err := row.Scan(
+		&collection.ID,
+		&collection.Name,
+		&collection.UserID,
+		&collection.ReviewSettings.Scheduler,
+		&collection.ReviewSettings.NewCardsPerDay,
+		&collection.ReviewSettings.ReviewsPerDay,
+		&collection.ReviewSettings.LeechThreshold,
+		&collection.ReviewSettings.LeechAction,
+		&collection.ReviewSettings.CardTemplates,
+		&collection.Description,
+		&collection.DefaultTranslatedFrom,
+		&collection.DefaultTranslatedTo,
+		&collection.Color,
+		&collection.Icon,
+		&collection.Archived,
+		&counts.Words,
+		&counts.Due,
+		&counts.New,
+	)
+	if err != nil {
+		return collection, fmt.Errorf("failed to scan collection row: %w", err)
+	}
+	collection.Counts = &counts
+	return collection, nil
 }
 
-func (t *translationRepository) CreateCollectionByUserID(ctx context.Context, userID int, collectionName string) (int, error) {
+func (t *translationRepository) CreateCollectionByUserID(ctx context.Context, userID int, collectionName string, scheduler string) (int, error) {
 	var collectionID int
 	query := `
-		INSERT INTO public.collections (collection_name, user_id)
-		VALUES ($1, $2)
+		INSERT INTO public.collections (collection_name, user_id, scheduler)
+		VALUES ($1, $2, $3)
 		RETURNING id
 	`
-	err := t.conn.QueryRow(ctx, query, collectionName, userID).Scan(&collectionID)
+	err := t.conn.QueryRow(ctx, query, collectionName, userID, scheduler).Scan(&collectionID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create collection: %w", err)
 	}
@@ -173,95 +279,363 @@ func (t *translationRepository) DeleteCollectionByUserID(ctx context.Context, us
 	return nil
 }
 
-func (t *translationRepository) SaveToCollectionLexicalItem(ctx context.Context, collectionID, translationID int) (int, error) {
-	_, err := t.conn.Exec(
+// SaveToCollection adds the translation to the user's collection in a single transaction,
+// a card is created for every template of the collection and the first card is returned.
+// Zero collectionID means the first collection of the user, archived ones last, it is created when the user has none.
+// Saving a translation that is already in the collection returns the existing card
+func (t *translationRepository) SaveToCollection(ctx context.Context, userID, collectionID, translationID int) (domain.SavedTranslation, error) {
+	tx, err := t.conn.Begin(ctx)
+	if err != nil {
+		return domain.SavedTranslation{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if collectionID == 0 {
+		err = tx.QueryRow(ctx, "SELECT id FROM collections WHERE user_id = $1 ORDER BY archived, id LIMIT 1", userID).Scan(&collectionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(
+				ctx,
+				"INSERT INTO collections (user_id, collection_name) VALUES ($1, $2) RETURNING id",
+				userID,
+				domain.DefaultCollectionName,
+			).Scan(&collectionID)
+		}
+		if err != nil {
+			return domain.SavedTranslation{}, fmt.Errorf("failed to get default collection: %w", err)
+		}
+	} else {
+		err = tx.QueryRow(ctx, "SELECT id FROM collections WHERE id = $1 AND user_id = $2", collectionID, userID).Scan(&collectionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.SavedTranslation{}, domain.ErrCollectionNotFound
+		}
+		if err != nil {
+			return domain.SavedTranslation{}, fmt.Errorf("failed to get collection: %w", err)
+		}
+	}
+
+	saved := domain.SavedTranslation{CollectionID: collectionID}
+	_, err = tx.Exec(
 		ctx,
-		"INSERT INTO collection_translations (collection_id, translation_id, due) VALUES ($1, $2, $3)",
+		`INSERT INTO collection_translations (collection_id, translation_id, template, due)
+		 SELECT c.id, $2, template, $3
+		 FROM collections c
+		 CROSS JOIN unnest(c.card_templates) AS template
+		 WHERE c.id = $1
+		 ON CONFLICT (collection_id, translation_id, template) DO NOTHING`,
 		collectionID,
 		translationID,
-		time.Now(),
+		time.Now().UTC(),
 	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to assosiate translation with collection: %w", err)
+		return domain.SavedTranslation{}, fmt.Errorf("failed to create cards: %w", err)
+	}
+	err = tx.QueryRow(
+		ctx,
+		"SELECT id FROM collection_translations WHERE collection_id = $1 AND translation_id = $2 ORDER BY id LIMIT 1",
+		collectionID,
+		translationID,
+	).Scan(&saved.CollectionTranslationID)
+	if err != nil {
+		return domain.SavedTranslation{}, fmt.Errorf("failed to assosiate translation with collection: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return domain.SavedTranslation{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return 0, nil
+	return saved, nil
+}
+
+// collectionTranslationColumns are the columns scanned by scanCollectionTranslation,
+// they are selected from collectionTranslationJoins
+const collectionTranslationColumns = `
+	ct.id,
+	ct.collection_id,
+	ct.translation_id,
+	ct.template,
+	ct.due,
+	ct.ease,
+	ct.interval_days,
+	ct.reps,
+	ct.lapses,
+	ct.stability,
+	ct.difficulty,
+	ct.last_review,
+	ct.tags,
+	ct.suspended,
+	ct.buried_until,
+	ct.created_at,
+	c.collection_name,
+	c.user_id,
+	c.scheduler,
+	c.new_cards_per_day,
+	c.reviews_per_day,
+	c.leech_threshold,
+	c.leech_action,
+	c.card_templates,
+	t.lexical_item,
+	t.meaning,
+	t.examples,
+	t.translated_from,
+	t.translated_to,
+	t.translated_lexical_item,
+	t.translated_meaning,
+	t.translated_examples,
+	t.part_of_speech
+`
+
+const collectionTranslationJoins = `
+	FROM collection_translations ct
+	JOIN collections c ON ct.collection_id = c.id
+	JOIN translations t ON ct.translation_id = t.id
+`
+
+func scanCollectionTranslation(row pgx.Row) (domain.CollectionTranslation, error) {
+	var ct domain.CollectionTranslation
+	err := row.Scan(collectionTranslationDest(&ct)...)
+	return ct, err
 }
 
+// collectionTranslationDest are the destinations of collectionTranslationColumns,
+// the queries that select more columns scan them after these
+func collectionTranslationDest(ct *domain.CollectionTranslation) []any {
+	return []any{
+		&ct.ID,
+		&ct.Collection.ID,
+		&ct.Translation.ID,
+		&ct.Template,
+		&ct.Due,
+		&ct.Ease,
+		&ct.IntervalDays,
+		&ct.Reps,
+		&ct.Lapses,
+		&ct.Stability,
+		&ct.Difficulty,
+		&ct.LastReview,
+		&ct.Tags,
+		&ct.Suspended,
+		&ct.BuriedUntil,
+		&ct.CreatedAt,
+		&ct.Collection.Name,
+		&ct.Collection.UserID,
+		&ct.Collection.ReviewSettings.Scheduler,
+		&ct.Collection.ReviewSettings.NewCardsPerDay,
+		&ct.Collection.ReviewSettings.ReviewsPerDay,
+		&ct.Collection.ReviewSettings.LeechThreshold,
+		&ct.Collection.ReviewSettings.LeechAction,
+		&ct.Collection.ReviewSettings.CardTemplates,
+		&ct.Translation.OriginalLexicalItem,
+		&ct.Translation.OriginalMeaning,
+		&ct.Translation.OriginalExamples,
+		&ct.Translation.TranslatedFrom,
+		&ct.Translation.TranslatedTo,
+		&ct.Translation.TranslatedLexicalItem,
+		&ct.Translation.TranslatedMeaning,
+		&ct.Translation.TranslatedExamples,
+		&ct.Translation.PartOfSpeech,
+	}
+}
+
+func (t *translationRepository) queryCollectionTranslations(ctx context.Context, query string, args ...any) ([]domain.CollectionTranslation, error) {
+	rows, err := t.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-func (t *translationRepository) GetCollectionTranslations(ctx context.Context, collectionID int, translationIDs []int, userID int) ([]domain.CollectionTranslation, error) {
 	var translations []domain.CollectionTranslation
+	for rows.Next() {
+		ct, err := scanCollectionTranslation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection_translation row: %w", err)
+		}
+		translations = append(translations, ct)
+	}
+	return translations, rows.Err()
+}
 
-	query := `
-		SELECT 
-		    ct.id AS collection_translation_id, 
-		    ct.collection_id, 
-		    ct.translation_id, 
-			ct.due,
-		    c.collection_name, 
-		    c.user_id, 
-		    t.lexical_item, 
-		    t.meaning, 
-		    t.examples, 
-		    t.translated_from, 
-		    t.translated_to, 
-		    t.translated_lexical_item, 
-		    t.translated_meaning, 
-		    t.translated_examples
-		FROM 
-		    collection_translations ct
-		JOIN 
-		    collections c ON ct.collection_id = c.id
-		JOIN 
-		    translations t ON ct.translation_id = t.id
-		WHERE 
-		    c.id = $1
-		AND
-			c.user_id = $2
-	`
-	args := []interface{}{collectionID, userID}
+// translationCards sums up the cards of the translation of ct in its collection
+const translationCards = `
+	CROSS JOIN LATERAL (
+		SELECT bool_or(s.last_review IS NULL AND NOT s.suspended) AS has_new,
+			   min(s.due) FILTER (WHERE s.last_review IS NOT NULL AND NOT s.suspended) AS next_due
+		FROM collection_translations s
+		WHERE s.collection_id = ct.collection_id AND s.translation_id = ct.translation_id
+	) k
+`
+
+// translationSortKeys are the keys of the sorts of the translations, the id of the card makes the order total.
+// Sorted by due the translations with no reviewed card go last
+var translationSortKeys = map[string][]string{
+	domain.SortCreated:      {"ct.created_at", "ct.id"},
+	domain.SortDue:          {"k.next_due IS NULL", "COALESCE(k.next_due, 'epoch'::timestamp)", "ct.id"},
+	domain.SortAlphabetical: {"lower(t.lexical_item)", "ct.id"},
+}
 
-	if len(translationIDs) > 0 {
-		query += " AND t.id = ANY($3)"
-		args = append(args, translationIDs)
+// queryArgs collect the arguments of a query built out of optional conditions
+type queryArgs []any
+
+// add appends the argument and returns its placeholder
+func (a *queryArgs) add(arg any) string {
+	*a = append(*a, arg)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// GetCollectionTranslations lists a page of the translations of the collection, one per translation, the first card of each.
+// The filters on the due state and the tag match when any card of the translation matches
+func (t *translationRepository) GetCollectionTranslations(ctx context.Context, req domain.GetCollectionsTranslationsRequest) (domain.CollectionTranslationsPage, error) {
+	var args queryArgs
+	conditions := []string{
+		"c.id = " + args.add(req.CollectionID),
+		"c.user_id = " + args.add(req.UserID),
+		`NOT EXISTS (
+			SELECT 1 FROM collection_translations sibling
+			WHERE sibling.collection_id = ct.collection_id
+			AND sibling.translation_id = ct.translation_id
+			AND sibling.id < ct.id
+		)`,
+	}
+	if len(req.TranslationIDs) > 0 {
+		conditions = append(conditions, "t.id = ANY("+args.add(req.TranslationIDs)+")")
+	}
+	if req.TranslatedFrom != "" {
+		conditions = append(conditions, "t.translated_from = "+args.add(req.TranslatedFrom))
+	}
+	if req.TranslatedTo != "" {
+		conditions = append(conditions, "t.translated_to = "+args.add(req.TranslatedTo))
+	}
+	switch req.DueState {
+	case domain.DueStateNew:
+		conditions = append(conditions, "k.has_new")
+	case domain.DueStateDue:
+		conditions = append(conditions, "k.next_due <= "+args.add(req.Now.UTC()))
+	case domain.DueStateNotDue:
+		conditions = append(conditions, "NOT COALESCE(k.has_new, false) AND (k.next_due IS NULL OR k.next_due > "+args.add(req.Now.UTC())+")")
+	}
+	if req.Tag != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM collection_translations tagged
+			WHERE tagged.collection_id = ct.collection_id
+			AND tagged.translation_id = ct.translation_id
+			AND `+args.add(req.Tag)+` = ANY(tagged.tags)
+		)`)
+	}
+	if req.AddedSince != nil {
+		conditions = append(conditions, "ct.created_at >= "+args.add(req.AddedSince.UTC()))
+	}
+	if req.AddedUntil != nil {
+		conditions = append(conditions, "ct.created_at < "+args.add(req.AddedUntil.UTC()))
+	}
+
+	page := domain.CollectionTranslationsPage{Items: []domain.CollectionTranslation{}}
+	from := collectionTranslationJoins + translationCards
+	if req.Limit > 0 {
+		err := t.conn.QueryRow(ctx, "SELECT count(*)"+from+" WHERE "+strings.Join(conditions, " AND "), args...).Scan(&page.Total)
+		if err != nil {
+			return page, fmt.Errorf("failed to count translations of collection_id %d: %w", req.CollectionID, err)
+		}
+	}
+
+	keys := translationSortKeys[req.Sort]
+	if req.Cursor != nil {
+		var values []any
+		switch req.Sort {
+		case domain.SortCreated:
+			var createdAt time.Time
+			if req.Cursor.CreatedAt != nil {
+				createdAt = req.Cursor.CreatedAt.UTC()
+			}
+			values = []any{createdAt, req.Cursor.ID}
+		case domain.SortDue:
+			due := time.Unix(0, 0).UTC()
+			if req.Cursor.Due != nil {
+				due = req.Cursor.Due.UTC()
+			}
+			values = []any{req.Cursor.Due == nil, due, req.Cursor.ID}
+		case domain.SortAlphabetical:
+			values = []any{req.Cursor.Word, req.Cursor.ID}
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = args.add(value)
+		}
+		operator := ">"
+		if req.Order == domain.OrderDesc {
+			operator = "<"
+		}
+		conditions = append(conditions, "("+strings.Join(keys, ", ")+") "+operator+" ("+strings.Join(placeholders, ", ")+")")
+	}
+	orderBy := make([]string, len(keys))
+	for i, key := range keys {
+		orderBy[i] = key + " " + strings.ToUpper(req.Order)
+	}
+	query := "SELECT " + collectionTranslationColumns + ", k.next_due, lower(t.lexical_item)" + from +
+		" WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + strings.Join(orderBy, ", ")
+	if req.Limit > 0 {
+		query += " LIMIT " + args.add(req.Limit+1)
 	}
 
 	rows, err := t.conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve collection translations for collection_id %d: %w", collectionID, err)
+		return page, fmt.Errorf("failed to retrieve collection translations for collection_id %d: %w", req.CollectionID, err)
 	}
 	defer rows.Close()
 
+	var last domain.TranslationsCursor
 	for rows.Next() {
+		if req.Limit > 0 && len(page.Items) == req.Limit {
+			// a row past the page tells that there is a next one
+			page.NextCursor = last.Encode()
+			break
+		}
 		var ct domain.CollectionTranslation
-		var collection domain.Collection
-		var translation domain.Translation
-
-		if err := rows.Scan(
-			&ct.ID,
-			&collection.ID,
-			&translation.ID,
-			&ct.Due,
-			&collection.Name,
-			&collection.UserID,
-			&translation.OriginalLexicalItem,
-			&translation.OriginalMeaning,
-			&translation.OriginalExamples,
-			&translation.TranslatedFrom,
-			&translation.TranslatedTo,
-			&translation.TranslatedLexicalItem,
-			&translation.TranslatedMeaning,
-			&translation.TranslatedExamples,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan collection_translation row: %w", err)
+		var nextDue *time.Time
+		var word string
+		if err := rows.Scan(append(collectionTranslationDest(&ct), &nextDue, &word)...); err != nil {
+			return page, fmt.Errorf("failed to scan collection_translation row: %w", err)
 		}
+		page.Items = append(page.Items, ct)
+		last = domain.TranslationsCursor{Sort: req.Sort, Order: req.Order, ID: ct.ID}
+		switch req.Sort {
+		case domain.SortCreated:
+			last.CreatedAt = &ct.CreatedAt
+		case domain.SortDue:
+			last.Due = nextDue
+		case domain.SortAlphabetical:
+			last.Word = word
+		}
+	}
+	if req.Limit == 0 {
+		page.Total = len(page.Items)
+	}
+	return page, rows.Err()
+}
 
-		ct.Collection = collection
-		ct.Translation = translation
-
-		translations = append(translations, ct)
+// GetCollectionCards returns all the cards of the user's collection, every template of every translation
+func (t *translationRepository) GetCollectionCards(ctx context.Context, collectionID int, userID int) ([]domain.CollectionTranslation, error) {
+	query := "SELECT " + collectionTranslationColumns + collectionTranslationJoins + `
+		WHERE c.id = $1 AND c.user_id = $2
+		ORDER BY ct.id
+	`
+	cards, err := t.queryCollectionTranslations(ctx, query, collectionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve cards of collection_id %d: %w", collectionID, err)
 	}
+	return cards, nil
+}
 
-	return translations, nil
+// GetCollectionTranslation returns a single card of the user's collection
+func (t *translationRepository) GetCollectionTranslation(ctx context.Context, collectionTranslationID int, collectionID int, userID int) (*domain.CollectionTranslation, error) {
+	query := "SELECT " + collectionTranslationColumns + collectionTranslationJoins + `
+		WHERE ct.id = $1 AND c.id = $2 AND c.user_id = $3
+	`
+	ct, err := scanCollectionTranslation(t.conn.QueryRow(ctx, query, collectionTranslationID, collectionID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCollectionTranslationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve collection translation %d: %w", collectionTranslationID, err)
+	}
+	return &ct, nil
 }
 
 func (t *translationRepository) DeleteCollectionTranslations(ctx context.Context, translationIDs []int, collectionID int, userID int) error {
@@ -280,112 +654,215 @@ func (t *translationRepository) DeleteCollectionTranslations(ctx context.Context
 	return nil
 }
 
-func (t *translationRepository) UpdateCollectionTranslationDue(
-	ctx context.Context,
-	collectionTranslationID int,
-	collectionID int,
-	newDue time.Time,
-	userID int,
-) error {
-	_, err := t.conn.Exec(
+// UpdateReviewSettings applies the non-nil fields of settings to the user's collection, when the
+// card templates change the cards of the added templates are created or unsuspended and the cards of the removed ones are suspended
+func (t *translationRepository) UpdateReviewSettings(ctx context.Context, collectionID int, userID int, settings domain.ReviewSettingsUpdate) error {
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		return updateReviewSettings(ctx, tx, collectionID, userID, settings)
+	})
+}
+
+// UpdateCollection applies the non-nil fields of update to the user's collection, the review settings are updated
+// the way UpdateReviewSettings does it in the same transaction
+func (t *translationRepository) UpdateCollection(ctx context.Context, collectionID int, userID int, update domain.CollectionUpdate) error {
+	return pgx.BeginFunc(ctx, t.conn, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(
+			ctx,
+			`UPDATE collections
+			 SET collection_name = COALESCE($3, collection_name),
+				 description = COALESCE($4, description),
+				 default_translated_from = CASE WHEN $5::text IS NULL THEN default_translated_from ELSE NULLIF($5, '') END,
+				 default_translated_to = CASE WHEN $6::text IS NULL THEN default_translated_to ELSE NULLIF($6, '') END,
+				 color = CASE WHEN $7::text IS NULL THEN color ELSE NULLIF($7, '') END,
+				 icon = CASE WHEN $8::text IS NULL THEN icon ELSE NULLIF($8, '') END,
+				 archived = COALESCE($9, archived)
+			 WHERE id = $1 AND user_id = $2`,
+			collectionID,
+			userID,
+			update.Name,
+			update.Description,
+			update.DefaultTranslatedFrom,
+			update.DefaultTranslatedTo,
+			update.Color,
+			update.Icon,
+			update.Archived,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update collection: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return domain.ErrCollectionNotFound
+		}
+		if update.ReviewSettings == nil {
+			return nil
+		}
+		return updateReviewSettings(ctx, tx, collectionID, userID, *update.ReviewSettings)
+	})
+}
+
+// updateReviewSettings is UpdateReviewSettings in the transaction tx
+func updateReviewSettings(ctx context.Context, tx pgx.Tx, collectionID int, userID int, settings domain.ReviewSettingsUpdate) error {
+	cmdTag, err := tx.Exec(
 		ctx,
-		`UPDATE collection_translations
-		 SET due = $1
-		 WHERE id = $2
-		   AND collection_id = (
-			 SELECT id FROM collections
-			 WHERE id = $3 AND user_id = $4
-		   )`,
-		newDue,
-		collectionTranslationID,
+		`UPDATE collections
+		 SET scheduler = COALESCE($1, scheduler),
+			 new_cards_per_day = COALESCE($4, new_cards_per_day),
+			 reviews_per_day = COALESCE($5, reviews_per_day),
+			 leech_threshold = COALESCE($6, leech_threshold),
+			 leech_action = COALESCE($7, leech_action),
+			 card_templates = COALESCE($8, card_templates)
+		 WHERE id = $2 AND user_id = $3`,
+		settings.Scheduler,
 		collectionID,
 		userID,
+		settings.NewCardsPerDay,
+		settings.ReviewsPerDay,
+		settings.LeechThreshold,
+		settings.LeechAction,
+		settings.CardTemplates,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update review settings: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return domain.ErrCollectionNotFound
+	}
+	if settings.CardTemplates == nil {
+		return nil
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO collection_translations (collection_id, translation_id, template, due)
+		 SELECT c.id, saved.translation_id, template, $2
+		 FROM collections c
+		 CROSS JOIN unnest(c.card_templates) AS template
+		 CROSS JOIN (
+			SELECT DISTINCT translation_id FROM collection_translations WHERE collection_id = $1
+		 ) saved
+		 WHERE c.id = $1
+		 ON CONFLICT (collection_id, translation_id, template) DO NOTHING`,
+		collectionID,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create cards of the added templates: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE collection_translations
+		 SET suspended = FALSE, template_suspended = FALSE
+		 WHERE collection_id = $1 AND template = ANY($2) AND template_suspended`,
+		collectionID,
+		settings.CardTemplates,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update due date: %w", err)
+		return fmt.Errorf("failed to unsuspend cards of the added templates: %w", err)
+	}
+	// the cards of the removed templates keep their review history, the ones the user has
+	// suspended stay suspended when the template is added back
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE collection_translations
+		 SET suspended = TRUE, template_suspended = TRUE
+		 WHERE collection_id = $1 AND NOT template = ANY($2) AND NOT suspended`,
+		collectionID,
+		settings.CardTemplates,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to suspend cards of the removed templates: %w", err)
+	}
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE review_session_cards SET done = TRUE
+		 WHERE NOT done AND collection_translation_id IN (
+			SELECT id FROM collection_translations WHERE collection_id = $1 AND NOT template = ANY($2)
+		 )`,
+		collectionID,
+		settings.CardTemplates,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to take cards of the removed templates out of review sessions: %w", err)
 	}
 	return nil
 }
 
+// dailyDueQueue selects the due cards, that are neither suspended nor buried, of the collections $1 that fit into what is left of their
+// daily limits for the study day started at $2, the cards are due at $3. Archived collections have no due cards. A review counts as
+// a new card when it is the first review of a card that has never been reviewed
+const dailyDueQueue = `
+	daily_limits AS (
+		SELECT
+			c.id,
+			GREATEST(c.new_cards_per_day - COUNT(rl.id) FILTER (WHERE rl.prev_reps = 0 AND NOT EXISTS (
+				SELECT 1 FROM review_logs prev
+				WHERE prev.collection_translation_id = rl.collection_translation_id
+				AND prev.reviewed_at < rl.reviewed_at
+			)), 0) AS new_left,
+			GREATEST(c.reviews_per_day - COUNT(rl.id) FILTER (WHERE rl.prev_reps > 0 OR EXISTS (
+				SELECT 1 FROM review_logs prev
+				WHERE prev.collection_translation_id = rl.collection_translation_id
+				AND prev.reviewed_at < rl.reviewed_at
+			)), 0) AS reviews_left
+		FROM collections c
+		LEFT JOIN collection_translations x ON x.collection_id = c.id
+		LEFT JOIN review_logs rl ON rl.collection_translation_id = x.id AND rl.reviewed_at >= $2
+		WHERE c.id = ANY($1) AND NOT c.archived
+		GROUP BY c.id
+	),
+	due_queue AS (
+		SELECT r.id, FALSE AS is_new, r.due
+		FROM daily_limits l
+		CROSS JOIN LATERAL (
+			SELECT ct.id, ct.due
+			FROM collection_translations ct
+			WHERE ct.collection_id = l.id
+			AND ct.last_review IS NOT NULL
+			AND ct.due <= $3
+			AND NOT ct.suspended
+			AND (ct.buried_until IS NULL OR ct.buried_until <= $3)
+			ORDER BY ct.due, ct.id
+			LIMIT l.reviews_left
+		) r
+		UNION ALL
+		SELECT n.id, TRUE AS is_new, NULL::TIMESTAMP AS due
+		FROM daily_limits l
+		CROSS JOIN LATERAL (
+			SELECT ct.id
+			FROM collection_translations ct
+			WHERE ct.collection_id = l.id
+			AND ct.last_review IS NULL
+			AND NOT ct.suspended
+			AND (ct.buried_until IS NULL OR ct.buried_until <= $3)
+			ORDER BY ct.id
+			LIMIT l.new_left
+		) n
+	)
+`
+
+// GetDueCollectionTranslations returns the due cards of the collection within its daily limits,
+// the limits are counted from dayStart
 func (t *translationRepository) GetDueCollectionTranslations(
-    ctx context.Context,
-    collectionID int,
-    translationIDs []int,
-    userID int,
+	ctx context.Context,
+	collectionID int,
+	translationIDs []int,
+	userID int,
+	dayStart time.Time,
 ) ([]domain.CollectionTranslation, error) {
-    var translations []domain.CollectionTranslation
-
-    query := `
-        SELECT 
-            ct.id AS collection_translation_id, 
-            ct.collection_id, 
-            ct.translation_id, 
-            ct.due,
-            c.collection_name, 
-            c.user_id, 
-            t.lexical_item, 
-            t.meaning, 
-            t.examples, 
-            t.translated_from, 
-            t.translated_to, 
-            t.translated_lexical_item, 
-            t.translated_meaning, 
-            t.translated_examples
-        FROM 
-            collection_translations ct
-        JOIN 
-            collections c ON ct.collection_id = c.id
-        JOIN 
-            translations t ON ct.translation_id = t.id
-        WHERE 
-            c.id = $1
-        AND
-            c.user_id = $2
-        AND
-        	(ct.due IS NULL OR ct.due <= NOW())
-    `
-    args := []interface{}{collectionID, userID}
-
-    if len(translationIDs) > 0 {
-        query += " AND t.id = ANY($3)"
-        args = append(args, translationIDs)
-    }
-
-    rows, err := t.conn.Query(ctx, query, args...)
-    if err != nil {
-        return nil, fmt.Errorf("failed to retrieve due collection translations for collection_id %d: %w", collectionID, err)
-    }
-    defer rows.Close()
-
-    for rows.Next() {
-        var ct domain.CollectionTranslation
-        var collection domain.Collection
-        var translation domain.Translation
-
-        if err := rows.Scan(
-            &ct.ID,
-            &collection.ID,
-            &translation.ID,
-            &ct.Due,
-            &collection.Name,
-            &collection.UserID,
-            &translation.OriginalLexicalItem,
-            &translation.OriginalMeaning,
-            &translation.OriginalExamples,
-            &translation.TranslatedFrom,
-            &translation.TranslatedTo,
-            &translation.TranslatedLexicalItem,
-            &translation.TranslatedMeaning,
-            &translation.TranslatedExamples,
-        ); err != nil {
-            return nil, fmt.Errorf("failed to scan collection_translation row: %w", err)
-        }
-
-        ct.Collection = collection
-        ct.Translation = translation
-
-        translations = append(translations, ct)
-    }
-
-    return translations, nil
+	query := "WITH " + dailyDueQueue + "SELECT " + collectionTranslationColumns + collectionTranslationJoins + `
+		JOIN due_queue q ON q.id = ct.id
+		WHERE c.user_id = $4
+	`
+	args := []interface{}{[]int{collectionID}, dayStart, time.Now().UTC(), userID}
+
+	if len(translationIDs) > 0 {
+		query += " AND t.id = ANY($5)"
+		args = append(args, translationIDs)
+	}
+	query += " ORDER BY q.is_new, q.due, ct.id"
+
+	translations, err := t.queryCollectionTranslations(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve due collection translations for collection_id %d: %w", collectionID, err)
+	}
+	return translations, nil
 }
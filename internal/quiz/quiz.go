@@ -0,0 +1,110 @@
+// Package quiz builds multiple-choice questions over the cards of a collection
+package quiz
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/answer"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// Build returns up to size questions, the due cards are asked first and the rest in random order.
+// The distractors of a question are the answers of other translations of the same language pair,
+// the same part of speech and a similar length are preferred. Cards of a translation that has no
+// distractor and suspended cards are left out
+func Build(cards []domain.CollectionTranslation, size int, now time.Time, rng *rand.Rand) []domain.QuizQuestion {
+	order := rng.Perm(len(cards))
+	sort.SliceStable(order, func(i, j int) bool {
+		return isDue(cards[order[i]], now) && !isDue(cards[order[j]], now)
+	})
+
+	questions := make([]domain.QuizQuestion, 0, size)
+	asked := make(map[int]bool)
+	for _, i := range order {
+		if len(questions) == size {
+			break
+		}
+		card := cards[i]
+		if card.Suspended {
+			continue
+		}
+		// a question per translation, its sibling cards would give the answer away
+		if asked[card.Translation.ID] {
+			continue
+		}
+		distractors := pickDistractors(card, cards, rng)
+		if len(distractors) == 0 {
+			continue
+		}
+		asked[card.Translation.ID] = true
+		choices := append(distractors, card.ExpectedAnswer())
+		rng.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+		questions = append(questions, domain.QuizQuestion{
+			CollectionTranslationID: card.ID,
+			Template:                card.Template,
+			Prompt:                  card.Prompt(),
+			Choices:                 choices,
+		})
+	}
+	return questions
+}
+
+// IsCorrect reports whether the choice is the answer of the card
+func IsCorrect(card domain.CollectionTranslation, choice string) bool {
+	return answer.Normalize(choice) == answer.Normalize(card.ExpectedAnswer())
+}
+
+type candidate struct {
+	text  string
+	score float64
+}
+
+func pickDistractors(card domain.CollectionTranslation, cards []domain.CollectionTranslation, rng *rand.Rand) []string {
+	expected := card.ExpectedAnswer()
+	seen := map[string]bool{answer.Normalize(expected): true}
+	var candidates []candidate
+	for _, other := range cards {
+		if other.Translation.TranslatedFrom != card.Translation.TranslatedFrom || other.Translation.TranslatedTo != card.Translation.TranslatedTo {
+			continue
+		}
+		// the answer of the other translation as if it was asked by the same template
+		other.Template = card.Template
+		other.Cloze = nil
+		text := other.ExpectedAnswer()
+		key := answer.Normalize(text)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, candidate{text: text, score: score(card, other, expected, text) + rng.Float64()})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	distractors := make([]string, 0, domain.QuizChoices-1)
+	for _, c := range candidates {
+		if len(distractors) == domain.QuizChoices-1 {
+			break
+		}
+		distractors = append(distractors, c.text)
+	}
+	return distractors
+}
+
+// score ranks a distractor, the same part of speech weighs the most, then the length similarity.
+// It is up to 3 and the random jitter added to it is up to 1
+func score(card, other domain.CollectionTranslation, expected, text string) float64 {
+	s := 0.0
+	if card.Translation.PartOfSpeech != "" && card.Translation.PartOfSpeech == other.Translation.PartOfSpeech {
+		s += 2
+	}
+	a, b := float64(len([]rune(expected))), float64(len([]rune(text)))
+	return s + 1 - math.Abs(a-b)/math.Max(a, b)
+}
+
+func isDue(card domain.CollectionTranslation, now time.Time) bool {
+	return card.Due != nil && !card.Due.After(now)
+}
@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+// fsrsDefaultWeights are the default FSRS-4.5 parameters
+var fsrsDefaultWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// FSRS is the Free Spaced Repetition Scheduler v4.5, https://github.com/open-spaced-repetition/fsrs4anki/wiki/The-Algorithm
+type FSRS struct {
+	weights [17]float64
+	// requestRetention is the probability of recall the next review is scheduled for
+	requestRetention float64
+}
+
+func NewFSRS() FSRS {
+	return FSRS{weights: fsrsDefaultWeights, requestRetention: 0.9}
+}
+
+func (f FSRS) Schedule(card domain.CardState, grade domain.Grade, now time.Time) domain.CardState {
+	g := float64(grade)
+	if card.Stability == 0 && card.IntervalDays > 0 {
+		// the card was scheduled by SM-2 before, its interval is the best guess of the stability
+		card.Stability = float64(card.IntervalDays)
+		card.Difficulty = f.initialDifficulty(float64(domain.GradeGood))
+	}
+	if card.Stability == 0 {
+		card.Stability = f.weights[grade-1]
+		card.Difficulty = f.initialDifficulty(g)
+	} else {
+		var elapsedDays float64
+		if card.LastReview != nil {
+			elapsedDays = math.Max(0, now.Sub(*card.LastReview).Hours()/24)
+		}
+		retrievability := math.Pow(1+fsrsFactor*elapsedDays/card.Stability, fsrsDecay)
+		card.Difficulty = f.nextDifficulty(card.Difficulty, g)
+		if grade == domain.GradeAgain {
+			card.Stability = f.forgetStability(card.Difficulty, card.Stability, retrievability)
+		} else {
+			card.Stability = f.recallStability(card.Difficulty, card.Stability, retrievability, grade)
+		}
+	}
+	card.LastReview = &now
+
+	if grade == domain.GradeAgain {
+		// only forgetting a learned card is a lapse
+		if card.Reps > 0 {
+			card.Lapses++
+		}
+		card.Reps = 0
+		card.IntervalDays = 0
+		due := now.Add(relearnDelay)
+		card.Due = &due
+		return card
+	}
+	card.Reps++
+	card.IntervalDays = f.nextInterval(card.Stability)
+	card.Due = dueIn(now, card.IntervalDays)
+	return card
+}
+
+func (f FSRS) initialDifficulty(g float64) float64 {
+	return clampDifficulty(f.weights[4] - (g-3)*f.weights[5])
+}
+
+func (f FSRS) nextDifficulty(d, g float64) float64 {
+	next := d - f.weights[6]*(g-3)
+	// mean reversion towards the difficulty of a card first answered with Good
+	return clampDifficulty(f.weights[7]*f.initialDifficulty(3) + (1-f.weights[7])*next)
+}
+
+func (f FSRS) recallStability(d, s, r float64, grade domain.Grade) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if grade == domain.GradeHard {
+		hardPenalty = f.weights[15]
+	}
+	if grade == domain.GradeEasy {
+		easyBonus = f.weights[16]
+	}
+	return s * (1 + math.Exp(f.weights[8])*(11-d)*math.Pow(s, -f.weights[9])*(math.Exp((1-r)*f.weights[10])-1)*hardPenalty*easyBonus)
+}
+
+func (f FSRS) forgetStability(d, s, r float64) float64 {
+	return f.weights[11] * math.Pow(d, -f.weights[12]) * (math.Pow(s+1, f.weights[13]) - 1) * math.Exp((1-r)*f.weights[14])
+}
+
+func (f FSRS) nextInterval(s float64) int {
+	return clampInterval(int(math.Round(s / fsrsFactor * (math.Pow(f.requestRetention, 1/fsrsDecay) - 1))))
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Min(10, math.Max(1, d))
+}
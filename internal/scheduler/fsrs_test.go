@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+func TestFSRSFirstReview(t *testing.T) {
+	tests := []struct {
+		grade        domain.Grade
+		stability    float64
+		difficulty   float64
+		intervalDays int
+		reps         int
+	}{
+		{domain.GradeAgain, 0.4872, 7.6214, 0, 0},
+		{domain.GradeHard, 1.4003, 6.3916, 1, 1},
+		{domain.GradeGood, 3.7145, 5.1618, 4, 1},
+		{domain.GradeEasy, 13.8206, 3.932, 14, 1},
+	}
+	for _, tt := range tests {
+		replay(t, NewFSRS(), domain.CardState{}, []reviewStep{{
+			grade:        tt.grade,
+			stability:    tt.stability,
+			difficulty:   tt.difficulty,
+			intervalDays: tt.intervalDays,
+			reps:         tt.reps,
+		}})
+	}
+}
+
+func TestFSRSSchedule(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name  string
+		card  domain.CardState
+		steps []reviewStep
+	}{
+		{
+			name: "reviews on time and a lapse",
+			steps: []reviewStep{
+				{grade: domain.GradeGood, stability: 3.7145, difficulty: 5.1618, intervalDays: 4, reps: 1},
+				{after: 4 * day, grade: domain.GradeGood, stability: 14.808101, difficulty: 5.1618, intervalDays: 15, reps: 2},
+				{after: 15 * day, grade: domain.GradeHard, stability: 21.508548, difficulty: 6.031478, intervalDays: 22, reps: 3},
+				{after: 20 * day, grade: domain.GradeEasy, stability: 149.400659, difficulty: 5.134840, intervalDays: 149, reps: 4},
+				{after: 60 * day, grade: domain.GradeAgain, stability: 7.932159, difficulty: 6.875031, intervalDays: 0, reps: 0, lapses: 1},
+				{after: relearnDelay, grade: domain.GradeGood, stability: 7.946087, difficulty: 6.821921, intervalDays: 8, reps: 1, lapses: 1},
+			},
+		},
+		{
+			name: "card scheduled by SM-2 before",
+			card: domain.CardState{Ease: 2.5, IntervalDays: 10, Reps: 3, LastReview: ptr(time.Date(2024, time.February, 20, 9, 0, 0, 0, time.UTC))},
+			steps: []reviewStep{
+				{grade: domain.GradeGood, ease: 2.5, stability: 34.407465, difficulty: 5.1618, intervalDays: 34, reps: 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay(t, NewFSRS(), tt.card, tt.steps)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
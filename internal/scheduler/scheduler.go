@@ -0,0 +1,47 @@
+// Package scheduler implements the spaced repetition algorithms that decide when a card is due next
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// relearnDelay is how soon a forgotten card is shown again
+const relearnDelay = 10 * time.Minute
+
+// maxIntervalDays caps intervals at roughly a hundred years
+const maxIntervalDays = 36500
+
+// Scheduler computes the state of a card after it has been answered with grade at now
+type Scheduler interface {
+	Schedule(card domain.CardState, grade domain.Grade, now time.Time) domain.CardState
+}
+
+// New returns the scheduler registered under name, see domain.SchedulerSM2 and domain.SchedulerFSRS
+func New(name string) (Scheduler, error) {
+	switch name {
+	case domain.SchedulerSM2, "":
+		return SM2{}, nil
+	case domain.SchedulerFSRS:
+		return NewFSRS(), nil
+	default:
+		return nil, fmt.Errorf("unsupported scheduler %q", name)
+	}
+}
+
+func dueIn(now time.Time, intervalDays int) *time.Time {
+	due := now.AddDate(0, 0, intervalDays)
+	return &due
+}
+
+func clampInterval(days int) int {
+	if days < 1 {
+		return 1
+	}
+	if days > maxIntervalDays {
+		return maxIntervalDays
+	}
+	return days
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+const (
+	sm2InitialEase = 2.5
+	sm2MinEase     = 1.3
+)
+
+// SM2 is the SuperMemo 2 algorithm, https://super-memory.com/english/ol/sm2.htm
+type SM2 struct{}
+
+func (SM2) Schedule(card domain.CardState, grade domain.Grade, now time.Time) domain.CardState {
+	if card.Ease == 0 {
+		card.Ease = sm2InitialEase
+	}
+	q := sm2Quality(grade)
+	card.Ease = math.Max(sm2MinEase, card.Ease+(0.1-(5-q)*(0.08+(5-q)*0.02)))
+	card.LastReview = &now
+
+	if grade == domain.GradeAgain {
+		// only forgetting a learned card is a lapse
+		if card.Reps > 0 {
+			card.Lapses++
+		}
+		card.Reps = 0
+		card.IntervalDays = 0
+		due := now.Add(relearnDelay)
+		card.Due = &due
+		return card
+	}
+
+	switch card.Reps {
+	case 0:
+		card.IntervalDays = 1
+	case 1:
+		card.IntervalDays = 6
+	default:
+		card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.Ease))
+	}
+	card.IntervalDays = clampInterval(card.IntervalDays)
+	card.Reps++
+	card.Due = dueIn(now, card.IntervalDays)
+	return card
+}
+
+// sm2Quality maps the four grades onto the 0-5 quality scale of SM-2
+func sm2Quality(grade domain.Grade) float64 {
+	switch grade {
+	case domain.GradeAgain:
+		return 1
+	case domain.GradeHard:
+		return 3
+	case domain.GradeEasy:
+		return 5
+	default:
+		return 4
+	}
+}
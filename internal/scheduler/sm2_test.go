@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// reviewStep is a review made after the given time since the previous one and the state it has to produce
+type reviewStep struct {
+	after        time.Duration
+	grade        domain.Grade
+	ease         float64
+	stability    float64
+	difficulty   float64
+	intervalDays int
+	reps         int
+	lapses       int
+}
+
+// replay reviews a new card step by step and compares every resulting state with the step
+func replay(t *testing.T, s Scheduler, card domain.CardState, steps []reviewStep) {
+	t.Helper()
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for i, step := range steps {
+		now = now.Add(step.after)
+		card = s.Schedule(card, step.grade, now)
+		if math.Abs(card.Ease-step.ease) > 1e-9 {
+			t.Errorf("step %d: ease = %v, want %v", i, card.Ease, step.ease)
+		}
+		if math.Abs(card.Stability-step.stability) > 1e-4 {
+			t.Errorf("step %d: stability = %.6f, want %.6f", i, card.Stability, step.stability)
+		}
+		if math.Abs(card.Difficulty-step.difficulty) > 1e-4 {
+			t.Errorf("step %d: difficulty = %.6f, want %.6f", i, card.Difficulty, step.difficulty)
+		}
+		if card.IntervalDays != step.intervalDays || card.Reps != step.reps || card.Lapses != step.lapses {
+			t.Errorf("step %d: interval, reps, lapses = %d, %d, %d, want %d, %d, %d",
+				i, card.IntervalDays, card.Reps, card.Lapses, step.intervalDays, step.reps, step.lapses)
+		}
+		wantDue := now.AddDate(0, 0, step.intervalDays)
+		if step.grade == domain.GradeAgain {
+			wantDue = now.Add(relearnDelay)
+		}
+		if card.Due == nil || !card.Due.Equal(wantDue) {
+			t.Errorf("step %d: due = %v, want %v", i, card.Due, wantDue)
+		}
+		if card.LastReview == nil || !card.LastReview.Equal(now) {
+			t.Errorf("step %d: lastReview = %v, want %v", i, card.LastReview, now)
+		}
+	}
+}
+
+func TestSM2Schedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		card  domain.CardState
+		steps []reviewStep
+	}{
+		{
+			name: "learning and a lapse",
+			steps: []reviewStep{
+				{grade: domain.GradeGood, ease: 2.5, intervalDays: 1, reps: 1},
+				{after: 24 * time.Hour, grade: domain.GradeGood, ease: 2.5, intervalDays: 6, reps: 2},
+				{after: 6 * 24 * time.Hour, grade: domain.GradeGood, ease: 2.5, intervalDays: 15, reps: 3},
+				{after: 15 * 24 * time.Hour, grade: domain.GradeEasy, ease: 2.6, intervalDays: 39, reps: 4},
+				{after: 39 * 24 * time.Hour, grade: domain.GradeHard, ease: 2.46, intervalDays: 96, reps: 5},
+				{after: 96 * 24 * time.Hour, grade: domain.GradeAgain, ease: 1.92, intervalDays: 0, reps: 0, lapses: 1},
+				{after: relearnDelay, grade: domain.GradeGood, ease: 1.92, intervalDays: 1, reps: 1, lapses: 1},
+			},
+		},
+		{
+			name: "again on a new card is not a lapse",
+			steps: []reviewStep{
+				{grade: domain.GradeAgain, ease: 1.96, intervalDays: 0},
+				{after: relearnDelay, grade: domain.GradeHard, ease: 1.82, intervalDays: 1, reps: 1},
+			},
+		},
+		{
+			name: "ease never drops below the minimum",
+			card: domain.CardState{Ease: 1.4, IntervalDays: 10, Reps: 3},
+			steps: []reviewStep{
+				{grade: domain.GradeAgain, ease: sm2MinEase, intervalDays: 0, lapses: 1},
+				{after: relearnDelay, grade: domain.GradeHard, ease: sm2MinEase, intervalDays: 1, reps: 1, lapses: 1},
+			},
+		},
+		{
+			name: "interval is capped",
+			card: domain.CardState{Ease: 2.5, IntervalDays: 30000, Reps: 10},
+			steps: []reviewStep{
+				{grade: domain.GradeGood, ease: 2.5, intervalDays: maxIntervalDays, reps: 11},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay(t, SM2{}, tt.card, tt.steps)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+func (t TranslatorServer) GetAccountSettings(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	settings, err := t.translatorRepository.GetAccountSettings(c.Request().Context(), userID)
+	if err != nil {
+		t.logger.Error("failed to get account settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get account settings"})
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (t TranslatorServer) UpdateAccountSettings(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var update domain.AccountSettingsUpdate
+	if err := c.Bind(&update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	if update.Timezone != nil && !domain.IsTimezoneValid(*update.Timezone) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid time zone"})
+	}
+	if update.DayRolloverHour != nil && !domain.IsDayRolloverHourValid(*update.DayRolloverHour) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "dayRolloverHour must be between 0 and 23"})
+	}
+	settings, err := t.translatorRepository.UpdateAccountSettings(c.Request().Context(), userID, update)
+	if err != nil {
+		t.logger.Error("failed to update account settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to update account settings"})
+	}
+	return c.JSON(http.StatusOK, settings)
+}
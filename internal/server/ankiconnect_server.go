@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// ConnectAnkiConnect stores where the AnkiConnect add-on of the user listens, the collections are synced with it.
+// The server connects to the add-on itself, private addresses such as localhost are refused on sync
+// unless they are listed in ANKICONNECT_ALLOWED_ADDRESSES
+func (t TranslatorServer) ConnectAnkiConnect(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var req domain.AnkiConnection
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	req.Key = strings.TrimSpace(req.Key)
+	if len(req.URL) > 255 || !isHTTPURL(req.URL) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "url must be an http or https URL of up to 255 characters"})
+	}
+	if len(req.Key) > 255 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "key must be up to 255 characters"})
+	}
+	if err := t.translatorRepository.SetAnkiConnect(c.Request().Context(), userID, &req); err != nil {
+		t.logger.Error("failed to set ankiconnect", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to connect ankiconnect"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DisconnectAnkiConnect forgets the AnkiConnect add-on of the user, the notes already pushed stay in Anki
+func (t TranslatorServer) DisconnectAnkiConnect(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	if err := t.translatorRepository.SetAnkiConnect(c.Request().Context(), userID, nil); err != nil {
+		t.logger.Error("failed to remove ankiconnect", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to disconnect ankiconnect"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SyncCollectionWithAnki pushes the new translations of the collection to Anki and reconciles the schedules of the cards
+func (t TranslatorServer) SyncCollectionWithAnki(c echo.Context) error {
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	result, err := t.ankiSync.Sync(c.Request().Context(), userID, collectionID, time.Now().UTC())
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if errors.Is(err, domain.ErrAnkiConnectNotConnected) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if errors.Is(err, domain.ErrAddressNotAllowed) {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": "ankiconnect url must point at a public address", "result": result})
+	}
+	if err != nil {
+		t.logger.Error("failed to sync collection with anki", slog.Int("collectionID", collectionID), slog.Any("err", err.Error()))
+		status := http.StatusInternalServerError
+		var connectErr *domain.AnkiConnectError
+		if errors.As(err, &connectErr) {
+			status = upstreamStatus(c, err)
+		}
+		return c.JSON(status, map[string]any{"message": "failed to sync collection with anki", "result": result})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
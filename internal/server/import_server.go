@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/importer"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxImportFileSize = 20 << 20
+	maxImportRows     = 1000
+	// maxEnrichRows bounds the incomplete rows of an import, each of them is looked up by the translator one after another
+	maxEnrichRows = 50
+)
+
+// ImportCollectionTranslations adds the words of an uploaded file to the collection. The format is detected
+// unless it is given, the rows are either kept as they are or their missing fields are filled in by the translator.
+// Words that are already in the collection are skipped, every row is reported
+func (t TranslatorServer) ImportCollectionTranslations(c echo.Context) error {
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	mode := c.FormValue("mode")
+	if mode != "" && mode != domain.ImportModeKeep && mode != domain.ImportModeEnrich {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "mode must be keep or enrich"})
+	}
+	translateFrom, translateTo := c.FormValue("from"), c.FormValue("to")
+	if _, ok := domain.SupportedLanguages[translateFrom]; !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "original language is not supported"})
+	}
+	if _, ok := domain.SupportedLanguages[translateTo]; !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "target language is not supported"})
+	}
+	options := importer.Options{
+		Columns:   splitList(c.FormValue("columns")),
+		Separator: c.FormValue("separator"),
+	}
+	if err := options.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	options.Kindle, err = kindleFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	data, filename, failed, status := readUpload(c)
+	if failed {
+		return status
+	}
+	format := c.FormValue("format")
+	if format == "" {
+		format = importer.Detect(filename, data)
+	}
+	if !isImportFormat(format) {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":     "format is not supported",
+			"supported": importer.Formats,
+		})
+	}
+	if mode == "" {
+		// a Kindle vocabulary only has the looked up words
+		mode = domain.ImportModeKeep
+		if format == importer.FormatKindle {
+			mode = domain.ImportModeEnrich
+		}
+	}
+
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	ctx := c.Request().Context()
+	rows, err := importer.Parse(ctx, format, data, options)
+	if errors.Is(err, importer.ErrMalformed) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		t.logger.Error("failed to parse imported file", slog.String("format", format), slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to import"})
+	}
+	return t.importRows(c, userID, collectionID, format, mode, translateFrom, translateTo, rows)
+}
+
+// importRows stores the parsed rows into the collection and replies with the result of every row.
+// The rows are stored one by one without a transaction, so a failed or interrupted import keeps the rows
+// stored before it. Running the import again is safe: the words already in the collection are skipped as duplicates
+func (t TranslatorServer) importRows(c echo.Context, userID, collectionID int, format, mode, translateFrom, translateTo string, rows []importer.Row) error {
+	if len(rows) > maxImportRows {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("file has %d rows, at most %d can be imported at once", len(rows), maxImportRows)})
+	}
+	if mode == domain.ImportModeEnrich {
+		incomplete := 0
+		for _, row := range rows {
+			if isIncomplete(row.Translation) {
+				incomplete++
+			}
+		}
+		if incomplete > maxEnrichRows {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("file has %d rows to enrich, at most %d can be enriched at once", incomplete, maxEnrichRows)})
+		}
+	}
+	ctx := c.Request().Context()
+	found, err := t.hasCollection(ctx, userID, collectionID)
+	if err != nil {
+		t.logger.Error("failed to get collections", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to import"})
+	}
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	cards, err := t.translatorRepository.GetCollectionCards(ctx, collectionID, userID)
+	if err != nil {
+		t.logger.Error("failed to get collection cards", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to import"})
+	}
+	existing := make(map[string]bool, len(cards))
+	for _, card := range cards {
+		existing[importKey(card.Translation)] = true
+	}
+
+	resp := domain.ImportResponse{Format: format, Rows: make([]domain.ImportRowResult, 0, len(rows))}
+	for _, row := range rows {
+		if row.Translation.TranslatedFrom == "" {
+			row.Translation.TranslatedFrom = translateFrom
+		}
+		if row.Translation.TranslatedTo == "" {
+			row.Translation.TranslatedTo = translateTo
+		}
+		result := t.importRow(ctx, userID, collectionID, mode, row, existing)
+		switch result.Status {
+		case domain.ImportRowImported:
+			resp.Imported++
+		case domain.ImportRowDuplicate:
+			resp.Duplicates++
+		case domain.ImportRowFailed:
+			resp.Failed++
+		}
+		resp.Rows = append(resp.Rows, result)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// importRow stores a row into the collection, existing holds the words of the collection and the imported ones
+func (t TranslatorServer) importRow(ctx context.Context, userID, collectionID int, mode string, row importer.Row, existing map[string]bool) domain.ImportRowResult {
+	translation := row.Translation
+	translation.OriginalLexicalItem = strings.TrimSpace(translation.OriginalLexicalItem)
+	result := domain.ImportRowResult{Line: row.Line, LexicalItem: translation.OriginalLexicalItem}
+	failed := func(message string) domain.ImportRowResult {
+		result.Status = domain.ImportRowFailed
+		result.Error = message
+		return result
+	}
+	if _, ok := domain.SupportedLanguages[translation.TranslatedFrom]; !ok {
+		return failed("original language is not supported")
+	}
+	if _, ok := domain.SupportedLanguages[translation.TranslatedTo]; !ok {
+		return failed("target language is not supported")
+	}
+	if translation.OriginalLexicalItem == "" {
+		return failed("word is missing")
+	}
+	key := importKey(translation)
+	if existing[key] {
+		result.Status = domain.ImportRowDuplicate
+		return result
+	}
+	if translation.PartOfSpeech != "" {
+		translation.PartOfSpeech = domain.NormalizePartOfSpeech(translation.PartOfSpeech)
+	}
+
+	translationID := 0
+	switch {
+	case mode == domain.ImportModeKeep && strings.TrimSpace(translation.TranslatedLexicalItem) == "":
+		return failed("translation is missing")
+	case mode == domain.ImportModeEnrich && isIncomplete(translation):
+		if len(translation.OriginalLexicalItem) > maxLexicalItemLength {
+			return failed(fmt.Sprintf("max lexical item size is %d", maxLexicalItemLength))
+		}
+		lookedUp, err := t.lookupTranslation(ctx, strings.ToLower(translation.OriginalLexicalItem), translation.TranslatedFrom, translation.TranslatedTo, false)
+		if err != nil {
+			t.logger.Error("failed to translate imported word", slog.Int("line", row.Line), slog.Any("err", err.Error()))
+			var translationErr *domain.TranslationError
+			if errors.As(err, &translationErr) && translationErr.Code == domain.TranslationErrorInvalidTranslation {
+				return failed("couldn't translate")
+			}
+			return failed("translation provider is unavailable, try again later")
+		}
+		if isWordOnly(translation) && len(row.Context) == 0 {
+			// nothing of the row would differ from the looked up translation
+			translationID = lookedUp.ID
+		}
+		translation = enrich(translation, *lookedUp)
+	}
+	translation.OriginalExamples = withContext(row.Context, translation.OriginalExamples)
+	if translationID == 0 {
+		if err := domain.ValidateImportedTranslation(translation); err != nil {
+			return failed(err.Error())
+		}
+		var err error
+		translationID, err = t.translatorRepository.AddImportedTranslation(ctx, translation)
+		if err != nil {
+			t.logger.Error("failed to store imported translation", slog.Int("line", row.Line), slog.Any("err", err.Error()))
+			return failed("failed to store translation")
+		}
+	}
+	saved, err := t.translatorRepository.SaveToCollection(ctx, userID, collectionID, translationID)
+	if err != nil {
+		t.logger.Error("failed to save imported translation to collection", slog.Int("line", row.Line), slog.Any("err", err.Error()))
+		return failed("failed to save translation to collection")
+	}
+	existing[key] = true
+	result.Status = domain.ImportRowImported
+	result.CollectionTranslationID = saved.CollectionTranslationID
+	return result
+}
+
+// readUpload reads the file of the multipart form
+func readUpload(c echo.Context) ([]byte, string, bool, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", true, c.JSON(http.StatusBadRequest, map[string]string{"error": "file is required"})
+	}
+	if header.Size > maxImportFileSize {
+		return nil, "", true, c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("file is larger than %d MB", maxImportFileSize>>20)})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", true, c.JSON(http.StatusBadRequest, map[string]string{"error": "failed to read file"})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		return nil, "", true, c.JSON(http.StatusBadRequest, map[string]string{"error": "failed to read file"})
+	}
+	return data, header.Filename, false, nil
+}
+
+// kindleFilter reads the books and the time bounds of the lookups imported from a Kindle vocabulary,
+// a book is given by its title, ASIN or id and can be repeated
+func kindleFilter(c echo.Context) (importer.KindleFilter, error) {
+	var filter importer.KindleFilter
+	form, err := c.FormParams()
+	if err != nil {
+		return filter, fmt.Errorf("invalid form")
+	}
+	for _, book := range form["book"] {
+		if book = strings.TrimSpace(book); book != "" {
+			filter.Books = append(filter.Books, book)
+		}
+	}
+	if since := c.FormValue("since"); since != "" {
+		if filter.Since, err = domain.ParseDateBound(since, false); err != nil {
+			return filter, fmt.Errorf("since: %w", err)
+		}
+	}
+	if until := c.FormValue("until"); until != "" {
+		if filter.Until, err = domain.ParseDateBound(until, true); err != nil {
+			return filter, fmt.Errorf("until: %w", err)
+		}
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, fmt.Errorf("since must be before until")
+	}
+	return filter, nil
+}
+
+// withContext puts the sentences the word was met in before the examples, an example is kept once.
+// A sentence too long for the translations table is cut at a word and ends with an ellipsis
+func withContext(context, examples []string) []string {
+	if len(context) == 0 {
+		return examples
+	}
+	result := make([]string, 0, len(context)+len(examples))
+	for _, sentence := range context {
+		if sentence = truncate(sentence, domain.MaxTranslationFieldLength); !slices.Contains(result, sentence) {
+			result = append(result, sentence)
+		}
+	}
+	for _, example := range examples {
+		if !slices.Contains(result, example) {
+			result = append(result, example)
+		}
+	}
+	return result
+}
+
+// truncate cuts the text to at most limit runes, at the last space when there is one
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit-1])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
+
+func isImportFormat(format string) bool {
+	for _, supported := range importer.Formats {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// importKey identifies a word of a collection, the case of the word is ignored
+func importKey(t domain.Translation) string {
+	return strings.ToLower(strings.TrimSpace(t.OriginalLexicalItem)) + "|" + t.TranslatedFrom + "|" + t.TranslatedTo
+}
+
+// isIncomplete tells whether the translator has something to fill in
+func isIncomplete(t domain.Translation) bool {
+	return t.TranslatedLexicalItem == "" || t.OriginalMeaning == "" || len(t.OriginalExamples) == 0 ||
+		t.TranslatedMeaning == "" || len(t.TranslatedExamples) == 0 || t.PartOfSpeech == ""
+}
+
+func isWordOnly(t domain.Translation) bool {
+	return t.TranslatedLexicalItem == "" && t.OriginalMeaning == "" && len(t.OriginalExamples) == 0 &&
+		t.TranslatedMeaning == "" && len(t.TranslatedExamples) == 0 && t.PartOfSpeech == ""
+}
+
+// enrich fills the empty fields of the translation in with the looked up one, the given fields are kept
+func enrich(t, lookedUp domain.Translation) domain.Translation {
+	if t.TranslatedLexicalItem == "" {
+		t.TranslatedLexicalItem = lookedUp.TranslatedLexicalItem
+	}
+	if t.OriginalMeaning == "" {
+		t.OriginalMeaning = lookedUp.OriginalMeaning
+	}
+	if len(t.OriginalExamples) == 0 {
+		t.OriginalExamples = lookedUp.OriginalExamples
+	}
+	if t.TranslatedMeaning == "" {
+		t.TranslatedMeaning = lookedUp.TranslatedMeaning
+	}
+	if len(t.TranslatedExamples) == 0 {
+		t.TranslatedExamples = lookedUp.TranslatedExamples
+	}
+	if t.PartOfSpeech == "" {
+		t.PartOfSpeech = lookedUp.PartOfSpeech
+	}
+	return t
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+func TestWithContext(t *testing.T) {
+	long := strings.Repeat("word ", domain.MaxTranslationFieldLength)
+	tests := []struct {
+		name     string
+		context  []string
+		examples []string
+		want     []string
+	}{
+		{"no context", nil, []string{"Our house is old."}, []string{"Our house is old."}},
+		{"sentence first", []string{"He was running home."}, []string{"I run every day."}, []string{"He was running home.", "I run every day."}},
+		{"sentence is an example", []string{"I run every day."}, []string{"I like it.", "I run every day."}, []string{"I run every day.", "I like it."}},
+		{"long sentence", []string{long}, nil, []string{truncate(long, domain.MaxTranslationFieldLength)}},
+	}
+	for _, tt := range tests {
+		if got := withContext(tt.context, tt.examples); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: withContext(%q, %q) = %q, want %q", tt.name, tt.context, tt.examples, got, tt.want)
+		}
+	}
+}
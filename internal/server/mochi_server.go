@@ -0,0 +1,125 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// ConnectMochi stores the Mochi API token of the user, the collections are synced with it
+func (t TranslatorServer) ConnectMochi(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var req domain.MochiAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	req.APIToken = strings.TrimSpace(req.APIToken)
+	if req.APIToken == "" || len(req.APIToken) > 255 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "apiToken must be between 1 and 255 characters"})
+	}
+	if err := t.translatorRepository.SetMochiToken(c.Request().Context(), userID, &req.APIToken); err != nil {
+		t.logger.Error("failed to set mochi api token", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to connect mochi"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DisconnectMochi forgets the Mochi API token of the user, the cards already pushed stay in Mochi
+func (t TranslatorServer) DisconnectMochi(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	if err := t.translatorRepository.SetMochiToken(c.Request().Context(), userID, nil); err != nil {
+		t.logger.Error("failed to remove mochi api token", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to disconnect mochi"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetCollectionMochiDeck maps the collection to a Mochi deck, the next sync moves the pushed cards to it
+func (t TranslatorServer) SetCollectionMochiDeck(c echo.Context) error {
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var req domain.MochiDeckRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	req.DeckID = strings.TrimSpace(req.DeckID)
+	if req.DeckID == "" || len(req.DeckID) > 64 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "deckId must be between 1 and 64 characters"})
+	}
+	err = t.translatorRepository.SetMochiDeck(c.Request().Context(), userID, collectionID, req.DeckID)
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to set mochi deck", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to set mochi deck"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SyncCollectionToMochi pushes the collection to its Mochi deck
+func (t TranslatorServer) SyncCollectionToMochi(c echo.Context) error {
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	result, err := t.mochiSync.Sync(c.Request().Context(), userID, collectionID, time.Now())
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if errors.Is(err, domain.ErrMochiNotConnected) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		t.logger.Error("failed to sync collection to mochi", slog.Int("collectionID", collectionID), slog.Any("err", err.Error()))
+		status := http.StatusInternalServerError
+		var upstreamErr *domain.UpstreamError
+		if errors.As(err, &upstreamErr) || errors.Is(err, domain.ErrCircuitOpen) {
+			status = upstreamStatus(c, err)
+		}
+		return c.JSON(status, map[string]any{"message": "failed to sync collection to mochi", "result": result})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/quiz"
+	"github.com/labstack/echo/v4"
+)
+
+// GetQuiz returns multiple-choice questions over the cards of the collection
+func (t TranslatorServer) GetQuiz(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	size := domain.DefaultQuizSize
+	if sizeParam := c.QueryParam("size"); sizeParam != "" {
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < 1 || size > domain.MaxQuizSize {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "size must be between 1 and " + strconv.Itoa(domain.MaxQuizSize)})
+		}
+	}
+
+	ctx := c.Request().Context()
+	found, err := t.hasCollection(ctx, userID, collectionID)
+	if err != nil {
+		t.logger.Error("failed to get collections", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get quiz"})
+	}
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	cards, err := t.translatorRepository.GetCollectionCards(ctx, collectionID, userID)
+	if err != nil {
+		t.logger.Error("failed to get collection cards", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get quiz"})
+	}
+	for i := range cards {
+		withCloze(&cards[i])
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return c.JSON(http.StatusOK, quiz.Build(cards, size, time.Now(), rng))
+}
+
+// AnswerQuiz grades the chosen answers and records them. A quiz is a practice, the cards are not rescheduled
+// and no review is logged. The results are only recorded when all the cards have been looked up
+func (t TranslatorServer) AnswerQuiz(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	var request domain.QuizAnswersRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	if len(request.Answers) > domain.MaxQuizSize {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "at most " + strconv.Itoa(domain.MaxQuizSize) + " answers can be sent at once"})
+	}
+
+	ctx := c.Request().Context()
+	answeredAt := time.Now().UTC()
+	results := make([]domain.QuizAnswerResult, 0, len(request.Answers))
+	var records []domain.QuizResult
+	for _, quizAnswer := range request.Answers {
+		result := domain.QuizAnswerResult{CollectionTranslationID: quizAnswer.CollectionTranslationID}
+		if quizAnswer.DurationMs != nil && *quizAnswer.DurationMs < 0 {
+			result.Error = "invalid durationMs"
+			results = append(results, result)
+			continue
+		}
+		card, err := t.translatorRepository.GetCollectionTranslation(ctx, quizAnswer.CollectionTranslationID, collectionID, userID)
+		if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+			result.Error = "collection translation not found"
+			results = append(results, result)
+			continue
+		}
+		if err != nil {
+			t.logger.Error("failed to get collection translation", slog.Any("err", err.Error()))
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to answer quiz"})
+		}
+		// the question of a cloze card has been asked with the blanked example
+		withCloze(card)
+		result.Correct = quiz.IsCorrect(*card, quizAnswer.Choice)
+		result.Expected = card.ExpectedAnswer()
+		results = append(results, result)
+		records = append(records, domain.QuizResult{
+			CollectionTranslationID: card.ID,
+			UserID:                  userID,
+			Correct:                 result.Correct,
+			DurationMs:              quizAnswer.DurationMs,
+			AnsweredAt:              answeredAt,
+		})
+	}
+	if err := t.translatorRepository.SaveQuizResults(ctx, records); err != nil {
+		t.logger.Error("failed to save quiz results", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to answer quiz"})
+	}
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/answer"
+	"github.com/bukhavtsov/artems-dictionary/internal/cloze"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/scheduler"
+	"github.com/labstack/echo/v4"
+)
+
+// reviewCard schedules the card answered with grade by the scheduler of its collection,
+// the new state is stored together with a review log. A card that lapses too often is handled as a leech,
+// the sibling cards of the same translation are buried for the rest of the user's study day.
+// Suspended and buried cards are rejected with domain.ErrCardNotReviewable and a card reviewed by
+// a concurrent request in the meantime with domain.ErrReviewConflict
+func (t TranslatorServer) reviewCard(
+	ctx context.Context,
+	collectionTranslationID, collectionID, userID int,
+	grade domain.Grade,
+	durationMs *int,
+	reviewedAt time.Time,
+) (*domain.CollectionTranslation, error) {
+	card, err := t.translatorRepository.GetCollectionTranslation(ctx, collectionTranslationID, collectionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !card.IsReviewable(reviewedAt) {
+		return nil, domain.ErrCardNotReviewable
+	}
+	cardScheduler, err := scheduler.New(card.Collection.ReviewSettings.Scheduler)
+	if err != nil {
+		return nil, err
+	}
+	prev := card.CardState
+	card.CardState = cardScheduler.Schedule(prev, grade, reviewedAt)
+	if card.DetectLeech(prev.Lapses) {
+		t.logger.Info("card became a leech", slog.Int("collectionTranslationID", collectionTranslationID), slog.Int("lapses", card.Lapses))
+	}
+	settings, err := t.translatorRepository.GetAccountSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	siblingsBuriedUntil := settings.DayStart(reviewedAt).AddDate(0, 0, 1)
+	review := domain.NewReviewLog(collectionTranslationID, userID, grade, prev, card.CardState, durationMs, reviewedAt)
+	err = t.translatorRepository.SaveReview(ctx, *card, review, siblingsBuriedUntil)
+	if err != nil {
+		return nil, err
+	}
+	withCloze(card)
+	return card, nil
+}
+
+// withCloze blanks the lexical item of a cloze card in its examples before the card is served
+func withCloze(card *domain.CollectionTranslation) {
+	if card.Template != domain.CardTemplateCloze {
+		return
+	}
+	if c, ok := cloze.Make(card.Translation.OriginalLexicalItem, card.Translation.TranslatedFrom, card.Translation.OriginalExamples); ok {
+		card.Cloze = &c
+	}
+}
+
+type typedAnswerResponse struct {
+	answer.Result
+	Card *domain.CollectionTranslation `json:"card"`
+}
+
+// AnswerCollectionTranslation checks the answer typed for the card and reviews it with the suggested grade
+func (t TranslatorServer) AnswerCollectionTranslation(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid id")
+	}
+	collectionID, err := strconv.Atoi(c.Param("collection_id"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid collection_id")
+	}
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var input domain.TypedAnswerInput
+	if err := c.Bind(&input); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid input")
+	}
+	if input.DurationMs != nil && *input.DurationMs < 0 {
+		return c.String(http.StatusBadRequest, "Invalid durationMs")
+	}
+
+	ctx := c.Request().Context()
+	card, err := t.translatorRepository.GetCollectionTranslation(ctx, id, collectionID, userID)
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection translation not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to get collection translation", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to check answer"})
+	}
+	withCloze(card)
+	if answer.TooLong(card.ExpectedAnswer(), input.Answer) {
+		return c.String(http.StatusBadRequest, "Answer is too long")
+	}
+	result := answer.Check(card.ExpectedAnswer(), input.Answer)
+	card, err = t.reviewCard(ctx, id, collectionID, userID, result.Grade, input.DurationMs, time.Now().UTC())
+	if errors.Is(err, domain.ErrCardNotReviewable) {
+		return c.JSON(http.StatusConflict, map[string]string{"message": "card is suspended or buried"})
+	}
+	if errors.Is(err, domain.ErrReviewConflict) {
+		return c.JSON(http.StatusConflict, map[string]string{"message": "card has been reviewed in the meantime"})
+	}
+	if err != nil {
+		t.logger.Error("failed to review card", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to check answer"})
+	}
+	return c.JSON(http.StatusOK, typedAnswerResponse{Result: result, Card: card})
+}
+
+func (t TranslatorServer) GetReviewHistory(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	collectionTranslationID, err := strconv.Atoi(c.Param("collectionTranslationID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionTranslationID"})
+	}
+	ctx := c.Request().Context()
+	// the card lookup checks that it belongs to the user's collection
+	_, err = t.translatorRepository.GetCollectionTranslation(ctx, collectionTranslationID, collectionID, userID)
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection translation not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to get collection translation", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get review history"})
+	}
+	logs, err := t.translatorRepository.GetReviewLogs(ctx, collectionTranslationID, userID)
+	if err != nil {
+		t.logger.Error("failed to get review logs", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get review history"})
+	}
+	if logs == nil {
+		logs = []domain.ReviewLog{}
+	}
+	return c.JSON(http.StatusOK, logs)
+}
+
+func (t TranslatorServer) SuspendCollectionTranslation(c echo.Context) error {
+	return t.setSuspended(c, true)
+}
+
+func (t TranslatorServer) UnsuspendCollectionTranslation(c echo.Context) error {
+	return t.setSuspended(c, false)
+}
+
+func (t TranslatorServer) setSuspended(c echo.Context, suspended bool) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	collectionTranslationID, err := strconv.Atoi(c.Param("collectionTranslationID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionTranslationID"})
+	}
+	err = t.translatorRepository.SetCardSuspended(c.Request().Context(), collectionTranslationID, collectionID, userID, suspended)
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection translation not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to update card suspension", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to update card"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// BuryCollectionTranslation hides the card from the reviews until the next study day of the user
+func (t TranslatorServer) BuryCollectionTranslation(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	collectionTranslationID, err := strconv.Atoi(c.Param("collectionTranslationID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionTranslationID"})
+	}
+	ctx := c.Request().Context()
+	settings, err := t.translatorRepository.GetAccountSettings(ctx, userID)
+	if err != nil {
+		t.logger.Error("failed to get account settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to bury card"})
+	}
+	until := settings.DayStart(time.Now().UTC()).AddDate(0, 0, 1)
+	err = t.translatorRepository.BuryCard(ctx, collectionTranslationID, collectionID, userID, until)
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection translation not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to bury card", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to bury card"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
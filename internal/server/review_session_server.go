@@ -0,0 +1,257 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// maxReviewAnswers bounds the answers of a single request, each of them is applied one after another
+const maxReviewAnswers = 500
+
+// CreateReviewSession queues the due cards of the requested collections and returns the first batch of them
+func (t TranslatorServer) CreateReviewSession(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	var request domain.ReviewSessionRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	collectionIDs := uniqueIDs(request.CollectionIDs)
+	if len(collectionIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "collectionIds are required"})
+	}
+	batchSize, ok := reviewBatchSize(request.BatchSize)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid batchSize"})
+	}
+	newLimit := domain.MaxCardsPerDay
+	if request.NewLimit != nil {
+		if *request.NewLimit < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid newLimit"})
+		}
+		newLimit = *request.NewLimit
+	}
+	ctx := c.Request().Context()
+	settings, err := t.translatorRepository.GetAccountSettings(ctx, userID)
+	if err != nil {
+		t.logger.Error("failed to get account settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to create review session"})
+	}
+	now := time.Now()
+	sessionID, err := t.translatorRepository.CreateReviewSession(ctx, userID, collectionIDs, newLimit, now.UTC(), settings.DayStart(now))
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to create review session", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to create review session"})
+	}
+	session, err := t.reviewSession(c, sessionID, userID, batchSize)
+	if err != nil {
+		t.logger.Error("failed to get review session", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to create review session"})
+	}
+	return c.JSON(http.StatusCreated, session)
+}
+
+// GetReviewSession returns the next batch of the session queue, answered cards are not returned again
+func (t TranslatorServer) GetReviewSession(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	sessionID, err := strconv.Atoi(c.Param("sessionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid SessionID"})
+	}
+	batchSize := 0
+	if value := c.QueryParam("batchSize"); value != "" {
+		batchSize, err = strconv.Atoi(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid batchSize"})
+		}
+	}
+	batchSize, ok := reviewBatchSize(batchSize)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid batchSize"})
+	}
+	session, err := t.reviewSession(c, sessionID, userID, batchSize)
+	if errors.Is(err, domain.ErrReviewSessionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "review session not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to get review session", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get review session"})
+	}
+	return c.JSON(http.StatusOK, session)
+}
+
+// AnswerReviewSession applies a bulk of answers in the order they were given, answers that
+// were already applied are reported as duplicates so that an offline client can safely resend them
+func (t TranslatorServer) AnswerReviewSession(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	sessionID, err := strconv.Atoi(c.Param("sessionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid SessionID"})
+	}
+	var request domain.ReviewAnswersRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	if len(request.Answers) > maxReviewAnswers {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("got %d answers, at most %d can be sent at once", len(request.Answers), maxReviewAnswers)})
+	}
+	ctx := c.Request().Context()
+	_, err = t.translatorRepository.GetReviewSession(ctx, sessionID, userID)
+	if errors.Is(err, domain.ErrReviewSessionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "review session not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to get review session", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to answer review session"})
+	}
+
+	now := time.Now().UTC()
+	answers := request.Answers
+	for i := range answers {
+		// answers without a time are taken as given now, answers from the future are clamped to now
+		if answers[i].ReviewedAt == nil || answers[i].ReviewedAt.After(now) {
+			answers[i].ReviewedAt = &now
+		}
+		reviewedAt := answers[i].ReviewedAt.UTC()
+		answers[i].ReviewedAt = &reviewedAt
+	}
+	sort.SliceStable(answers, func(i, j int) bool {
+		return answers[i].ReviewedAt.Before(*answers[j].ReviewedAt)
+	})
+
+	results := make([]domain.ReviewAnswerResult, 0, len(answers))
+	for _, answer := range answers {
+		results = append(results, t.answerSessionCard(c, sessionID, userID, answer))
+	}
+	session, err := t.translatorRepository.GetReviewSession(ctx, sessionID, userID)
+	if err != nil {
+		t.logger.Error("failed to get review session", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to answer review session"})
+	}
+	return c.JSON(http.StatusOK, domain.ReviewAnswersResponse{Results: results, Remaining: session.Remaining})
+}
+
+func (t TranslatorServer) answerSessionCard(c echo.Context, sessionID, userID int, answer domain.ReviewAnswer) domain.ReviewAnswerResult {
+	result := domain.ReviewAnswerResult{CollectionTranslationID: answer.CollectionTranslationID, Status: domain.ReviewAnswerFailed}
+	if !answer.Grade.IsValid() {
+		result.Error = "invalid grade"
+		return result
+	}
+	if answer.DurationMs != nil && *answer.DurationMs < 0 {
+		result.Error = "invalid durationMs"
+		return result
+	}
+	ctx := c.Request().Context()
+	sessionCard, err := t.translatorRepository.GetReviewSessionCard(ctx, sessionID, answer.CollectionTranslationID)
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		result.Error = "card is not in the review session"
+		return result
+	}
+	if err != nil {
+		t.logger.Error("failed to get review session card", slog.Any("err", err.Error()))
+		result.Error = "failed to answer"
+		return result
+	}
+	if sessionCard.Done || (sessionCard.LastAnsweredAt != nil && !answer.ReviewedAt.After(*sessionCard.LastAnsweredAt)) {
+		result.Status = domain.ReviewAnswerDuplicate
+		return result
+	}
+	card, err := t.reviewCard(ctx, answer.CollectionTranslationID, sessionCard.CollectionID, userID, answer.Grade, answer.DurationMs, *answer.ReviewedAt)
+	if errors.Is(err, domain.ErrCardNotReviewable) {
+		result.Error = "card is suspended or buried"
+		return result
+	}
+	if errors.Is(err, domain.ErrReviewConflict) {
+		result.Error = "card has been reviewed in the meantime"
+		return result
+	}
+	if err != nil {
+		t.logger.Error("failed to review card", slog.Any("err", err.Error()))
+		result.Error = "failed to answer"
+		return result
+	}
+	err = t.translatorRepository.MarkReviewSessionCard(ctx, sessionID, answer.CollectionTranslationID, *answer.ReviewedAt, answer.Grade == domain.GradeAgain && !card.Suspended)
+	if err != nil {
+		t.logger.Error("failed to mark review session card", slog.Any("err", err.Error()))
+		result.Error = "failed to answer"
+		return result
+	}
+	result.Status = domain.ReviewAnswerApplied
+	result.Due = card.Due
+	return result
+}
+
+func (t TranslatorServer) reviewSession(c echo.Context, sessionID, userID, batchSize int) (*domain.ReviewSession, error) {
+	ctx := c.Request().Context()
+	session, err := t.translatorRepository.GetReviewSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	session.Cards, err = t.translatorRepository.GetReviewSessionBatch(ctx, sessionID, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	if session.Cards == nil {
+		session.Cards = []domain.CollectionTranslation{}
+	}
+	for i := range session.Cards {
+		withCloze(&session.Cards[i])
+	}
+	return session, nil
+}
+
+func reviewBatchSize(batchSize int) (int, bool) {
+	if batchSize == 0 {
+		return domain.DefaultReviewBatchSize, true
+	}
+	if batchSize < 0 || batchSize > domain.MaxReviewBatchSize {
+		return 0, false
+	}
+	return batchSize, true
+}
+
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// SearchTranslations finds the words saved to any of the user's collections by their lexical items, meanings
+// and examples in both languages, typos are tolerated and the results come ranked with the matches highlighted
+func (t TranslatorServer) SearchTranslations(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	q := domain.SearchQuery{
+		UserID: userID,
+		Query:  strings.TrimSpace(c.QueryParam("q")),
+		Limit:  domain.DefaultSearchLimit,
+	}
+	if q.Query == "" || utf8.RuneCountInString(q.Query) > domain.MaxSearchQueryLength {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "q must be between 1 and " + strconv.Itoa(domain.MaxSearchQueryLength) + " characters"})
+	}
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		q.Limit, err = strconv.Atoi(limitParam)
+		if err != nil || q.Limit < 1 || q.Limit > domain.MaxSearchLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and " + strconv.Itoa(domain.MaxSearchLimit)})
+		}
+	}
+	results, err := t.translatorRepository.SearchTranslations(c.Request().Context(), q)
+	if err != nil {
+		t.logger.Error("failed to search translations", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to search translations"})
+	}
+	return c.JSON(http.StatusOK, domain.SearchResponse{Query: q.Query, Results: results})
+}
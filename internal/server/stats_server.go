@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+type StatsRepository interface {
+	GetCardCounts(ctx context.Context, q domain.StatsQuery) (domain.CardCounts, error)
+	GetReviewsPerDay(ctx context.Context, q domain.StatsQuery) ([]domain.DayCount, error)
+	GetDueForecast(ctx context.Context, q domain.StatsQuery) ([]domain.DayCount, error)
+	GetRetention(ctx context.Context, q domain.StatsQuery) (passed int, total int, err error)
+}
+
+func (t TranslatorServer) GetStats(c echo.Context) error {
+	return t.learningStats(c, 0)
+}
+
+func (t TranslatorServer) GetCollectionStats(c echo.Context) error {
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	return t.learningStats(c, collectionID)
+}
+
+// learningStats responds with the stats of a collection, or of all user's collections when collectionID is zero
+func (t TranslatorServer) learningStats(c echo.Context, collectionID int) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	ctx := c.Request().Context()
+	settings, err := t.translatorRepository.GetAccountSettings(ctx, userID)
+	if err != nil {
+		t.logger.Error("failed to get account settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get stats"})
+	}
+	q := domain.StatsQuery{
+		UserID:          userID,
+		CollectionID:    collectionID,
+		Days:            domain.DefaultStatsDays,
+		Location:        settings.Location(),
+		DayRolloverHour: settings.DayRolloverHour,
+		Now:             time.Now().UTC(),
+	}
+	if daysParam := c.QueryParam("days"); daysParam != "" {
+		q.Days, err = strconv.Atoi(daysParam)
+		if err != nil || q.Days < 1 || q.Days > domain.MaxStatsDays {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "days must be between 1 and " + strconv.Itoa(domain.MaxStatsDays)})
+		}
+	}
+	if tz := c.QueryParam("tz"); tz != "" {
+		q.Location, err = time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid time zone"})
+		}
+	}
+
+	if collectionID != 0 {
+		found, err := t.hasCollection(ctx, userID, collectionID)
+		if err != nil {
+			t.logger.Error("failed to get collections", slog.Any("err", err.Error()))
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get stats"})
+		}
+		if !found {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+		}
+	}
+	stats, err := t.computeStats(ctx, q)
+	if err != nil {
+		t.logger.Error("failed to compute stats", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get stats"})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (t TranslatorServer) computeStats(ctx context.Context, q domain.StatsQuery) (*domain.LearningStats, error) {
+	counts, err := t.statsRepository.GetCardCounts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := t.statsRepository.GetReviewsPerDay(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	due, err := t.statsRepository.GetDueForecast(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	passed, total, err := t.statsRepository.GetRetention(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	today := q.Today()
+	stats := &domain.LearningStats{
+		DueToday:      counts.DueToday,
+		DueTomorrow:   counts.DueTomorrow,
+		New:           counts.New,
+		Learning:      counts.Learning,
+		Mature:        counts.Mature,
+		ReviewsPerDay: domain.ReviewsPerDay(reviews, today, q.Days),
+		Forecast:      domain.Forecast(due, today, domain.ForecastDays),
+	}
+	stats.CurrentStreak, stats.LongestStreak = domain.Streaks(reviews, today)
+	if total > 0 {
+		retention := float64(passed) / float64(total)
+		stats.RetentionRate = &retention
+	}
+	return stats, nil
+}
+
+func (t TranslatorServer) hasCollection(ctx context.Context, userID, collectionID int) (bool, error) {
+	_, err := t.translatorRepository.GetCollectionByID(ctx, collectionID, userID)
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
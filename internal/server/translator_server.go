@@ -3,11 +3,9 @@ package server
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"log/slog"
 	"math/rand"
@@ -15,8 +13,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/export"
 	"github.com/bukhavtsov/artems-dictionary/internal/usecase"
 	"github.com/labstack/echo/v4"
 )
@@ -29,12 +29,14 @@ type TranslatorServer struct {
 	refreshTokenDuration time.Duration
 	accessTokenDuration  time.Duration
 
-	chatGPTAPIURL string
-	apiKey        string
-
-	ttsAPIKey string
+	translator usecase.Translator
+	tts        TextToSpeechClient
+	exporters  export.Registry
+	mochiSync  *usecase.MochiSync
+	ankiSync   *usecase.AnkiSync
 
 	translatorRepository TranslatorRepository
+	statsRepository      StatsRepository
 }
 
 func NewTranslatorServer(
@@ -43,9 +45,12 @@ func NewTranslatorServer(
 	accessTokenDuration time.Duration,
 	refreshTokenDuration time.Duration,
 	translatorRepository TranslatorRepository,
-	logger slog.Logger, chatGPTAPIURL string,
-	apiKey string,
-	ttsAPIKey string,
+	statsRepository StatsRepository,
+	logger slog.Logger,
+	translator usecase.Translator,
+	tts TextToSpeechClient,
+	mochiSync *usecase.MochiSync,
+	ankiSync *usecase.AnkiSync,
 ) *TranslatorServer {
 	return &TranslatorServer{
 		authService:          authService,
@@ -53,26 +58,54 @@ func NewTranslatorServer(
 		accessTokenDuration:  accessTokenDuration,
 		refreshTokenDuration: refreshTokenDuration,
 		translatorRepository: translatorRepository,
+		statsRepository:      statsRepository,
 		logger:               logger,
-		chatGPTAPIURL:        chatGPTAPIURL,
-		apiKey:               apiKey,
-		ttsAPIKey:            ttsAPIKey,
+		translator:           translator,
+		tts:                  tts,
+		exporters:            export.NewRegistry(tts),
+		mochiSync:            mochiSync,
+		ankiSync:             ankiSync,
 	}
 }
 
 type TranslatorRepository interface {
 	AddTranslation(ctx context.Context, translation domain.Translation, translatedFrom, translatedTo string) (int, error)
+	UpdateTranslation(ctx context.Context, translationID int, translation domain.Translation) error
+	AddImportedTranslation(ctx context.Context, translation domain.Translation) (int, error)
+	SetMochiToken(ctx context.Context, userID int, token *string) error
+	SetMochiDeck(ctx context.Context, userID, collectionID int, deckID string) error
+	SetAnkiConnect(ctx context.Context, userID int, conn *domain.AnkiConnection) error
+	SearchTranslations(ctx context.Context, q domain.SearchQuery) ([]domain.SearchResult, error)
 	GetAllTranslations(ctx context.Context) ([]domain.Translation, error)
 	GetTranslation(ctx context.Context, lexicalItem, translateFrom, translateTo string) (*domain.Translation, error)
 	GetCollectionsByUserID(ctx context.Context, userID int) ([]domain.Collection, error)
-	CreateCollectionByUserID(ctx context.Context, userID int, collectionName string) (int, error)
+	GetCollectionByID(ctx context.Context, collectionID int, userID int) (*domain.Collection, error)
+	CreateCollectionByUserID(ctx context.Context, userID int, collectionName string, scheduler string) (int, error)
 	DeleteCollectionByUserID(ctx context.Context, userID int, collectionID int) error
-	GetCollectionTranslations(ctx context.Context, collectionID int, translationIDs []int, userID int) ([]domain.CollectionTranslation, error)
+	GetCollectionTranslations(ctx context.Context, req domain.GetCollectionsTranslationsRequest) (domain.CollectionTranslationsPage, error)
+	GetCollectionTranslation(ctx context.Context, collectionTranslationID int, collectionID int, userID int) (*domain.CollectionTranslation, error)
+	GetCollectionCards(ctx context.Context, collectionID int, userID int) ([]domain.CollectionTranslation, error)
 	DeleteCollectionTranslations(ctx context.Context, translationIDs []int, collectionID int, userID int) error
-	CreateCollection(ctx context.Context, userID int, collectionName string) (int, error)
-	SaveToCollectionLexicalItem(ctx context.Context, collectionID, translationID int) (int, error)
-	GetDueCollectionTranslations(ctx context.Context, collectionID int, translationIDs []int, userID int) ([]domain.CollectionTranslation, error)
-	UpdateCollectionTranslationDue(ctx context.Context, collectionTranslationID int, collection_id int, newDue time.Time, userID int) error
+	SaveToCollection(ctx context.Context, userID, collectionID, translationID int) (domain.SavedTranslation, error)
+	GetDueCollectionTranslations(ctx context.Context, collectionID int, translationIDs []int, userID int, dayStart time.Time) ([]domain.CollectionTranslation, error)
+	SaveReview(ctx context.Context, card domain.CollectionTranslation, review domain.ReviewLog, siblingsBuriedUntil time.Time) error
+	SetCardSuspended(ctx context.Context, collectionTranslationID, collectionID, userID int, suspended bool) error
+	BuryCard(ctx context.Context, collectionTranslationID, collectionID, userID int, until time.Time) error
+	GetReviewLogs(ctx context.Context, collectionTranslationID int, userID int) ([]domain.ReviewLog, error)
+	SaveQuizResults(ctx context.Context, results []domain.QuizResult) error
+	UpdateReviewSettings(ctx context.Context, collectionID int, userID int, settings domain.ReviewSettingsUpdate) error
+	UpdateCollection(ctx context.Context, collectionID int, userID int, update domain.CollectionUpdate) error
+	CreateReviewSession(ctx context.Context, userID int, collectionIDs []int, newLimit int, now time.Time, dayStart time.Time) (int, error)
+	GetReviewSession(ctx context.Context, sessionID int, userID int) (*domain.ReviewSession, error)
+	GetReviewSessionBatch(ctx context.Context, sessionID int, limit int) ([]domain.CollectionTranslation, error)
+	GetReviewSessionCard(ctx context.Context, sessionID int, collectionTranslationID int) (*domain.ReviewSessionCard, error)
+	MarkReviewSessionCard(ctx context.Context, sessionID int, collectionTranslationID int, answeredAt time.Time, again bool) error
+	GetAccountSettings(ctx context.Context, userID int) (domain.AccountSettings, error)
+	UpdateAccountSettings(ctx context.Context, userID int, settings domain.AccountSettingsUpdate) (domain.AccountSettings, error)
+}
+
+type TextToSpeechClient interface {
+	Stream(ctx context.Context, text, language string) (io.ReadCloser, error)
 }
 
 func (t TranslatorServer) SignIn(c echo.Context) error {
@@ -129,6 +162,9 @@ func (t TranslatorServer) RefreshRefreshToken(c echo.Context) error {
 	return c.String(http.StatusOK, "successfully refreshed tokens")
 }
 
+// maxLexicalItemLength is the longest lexical item sent to the translator
+const maxLexicalItemLength = 80
+
 func (t TranslatorServer) Translate(c echo.Context) error {
 	sub, failed, err := t.GetSubFromToken(c)
 	if failed {
@@ -138,128 +174,112 @@ func (t TranslatorServer) Translate(c echo.Context) error {
 	err = c.Bind(&req)
 	if err != nil {
 		t.logger.Error("translate - failed to convert", slog.Any("err", err.Error()))
-		return c.String(http.StatusBadRequest, "invalid input")
+		return translationError(c, domain.TranslationErrorInvalidInput, "invalid input")
 	}
 	if _, ok := domain.SupportedLanguages[req.TranslateFrom]; !ok {
-		return c.String(http.StatusBadRequest, "original language is not supported")
+		return translationError(c, domain.TranslationErrorUnsupportedLanguage, "original language is not supported")
 	}
 	if _, ok := domain.SupportedLanguages[req.TranslateTo]; !ok {
-		return c.String(http.StatusBadRequest, "target language is not supported")
+		return translationError(c, domain.TranslationErrorUnsupportedLanguage, "target language is not supported")
 	}
-	const maxLexicalItemLength = 80
 	if len(req.LexicalItem) > maxLexicalItemLength {
-		return c.String(http.StatusBadRequest, fmt.Sprintf("max lexical item size is %d", maxLexicalItemLength))
-	}
-	req.LexicalItem = strings.ToLower(req.LexicalItem)
-	promptTemplate := "Translate the lexical item: '%s', from '%s' to '%s'. Provide response in JSON format as follows: translatedFrom: string; translatedTo: string; originalLexicalItem: string; originalMeaning: string; originalExamples: [string, string]; translatedLexicalItem: string; translatedMeaning: string; translatedExamples: [string, string];. Ensure that 'originalMeaning' is in the original language ('translatedFrom')."
-	prompt := fmt.Sprintf(promptTemplate, req.LexicalItem, req.TranslateFrom, req.TranslateTo)
-	lexicalItem, err := t.callChatGPTAPI(prompt)
-	if err != nil {
-		t.logger.Error("failed to make a call to chatgpt", slog.Any("err", err.Error()))
-		return c.String(http.StatusInternalServerError, "server error try again later")
-	}
-	if domain.IsTranslationNilOrEmpty(lexicalItem) {
-		return c.String(http.StatusBadRequest, "couldn't translate")
+		return translationError(c, domain.TranslationErrorLexicalItemTooLong, fmt.Sprintf("max lexical item size is %d", maxLexicalItemLength))
 	}
-	if req.SavingEnabled {
-		go func() {
-			ctx := context.Background()
-			translationID, err := t.translatorRepository.AddTranslation(ctx, *lexicalItem, req.TranslateFrom, req.TranslateTo)
-			if err != nil {
-				t.logger.Error(err.Error())
-				return
-			}
-			t.addTranslationToCollection(ctx, sub, translationID, req.CollectionID)
-
-		}()
+	req.LexicalItem = strings.ToLower(strings.TrimSpace(req.LexicalItem))
+	if req.LexicalItem == "" {
+		return translationError(c, domain.TranslationErrorInvalidInput, "lexical item is required")
 	}
-	return c.JSON(http.StatusOK, lexicalItem)
-}
+	ctx := c.Request().Context()
 
-func (t TranslatorServer) addTranslationToCollection(ctx context.Context, sub string, translationID int, collectionID int) {
-	userID, err := strconv.Atoi(sub)
+	lexicalItem, err := t.lookupTranslation(ctx, req.LexicalItem, req.TranslateFrom, req.TranslateTo, req.ForceRefresh)
 	if err != nil {
-		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
-		return
+		t.logger.Error("failed to translate", slog.Any("err", err.Error()))
+		var translationErr *domain.TranslationError
+		if errors.As(err, &translationErr) {
+			switch translationErr.Code {
+			case domain.TranslationErrorInvalidTranslation:
+				return translationError(c, translationErr.Code, "couldn't translate")
+			case domain.TranslationErrorMalformedResponse:
+				return translationError(c, translationErr.Code, "translation provider replied with malformed data, try again later")
+			}
+		}
+		return c.JSON(upstreamStatus(c, err), domain.TranslationErrorResponse{
+			Code:    domain.TranslationErrorProviderUnavailable,
+			Message: "translation provider is unavailable, try again later",
+		})
 	}
-
-	if collectionID == 0 {
-		// set default collection
-		collections, err := t.translatorRepository.GetCollectionsByUserID(ctx, userID)
+	if req.SavingEnabled && lexicalItem.ID == 0 {
+		return translationError(c, domain.TranslationErrorStorageFailed, "server error try again later")
+	}
+	resp := domain.TranslationResponse{Translation: *lexicalItem}
+	if req.SavingEnabled {
+		saved, err := t.saveToCollection(ctx, sub, lexicalItem.ID, req.CollectionID)
 		if err != nil {
-			t.logger.Error("GetCollectionsByUserID failed", slog.Any("err", err.Error()))
-			return
-		}
-		var collectionID int
-		if len(collections) == 0 {
-			collectionID, err = t.translatorRepository.CreateCollection(ctx, userID, "default")
-			if err != nil {
-				t.logger.Error("CreateCollection failed", slog.Any("err", err.Error()))
-				return
+			t.logger.Error("failed to save translation to collection", slog.Any("err", err.Error()))
+			resp.SaveError = "failed to save translation to collection"
+			if errors.Is(err, domain.ErrCollectionNotFound) {
+				resp.SaveError = "collection not found"
 			}
 		} else {
-			collectionID = collections[0].ID
+			resp.Saved = true
+			resp.CollectionID = saved.CollectionID
+			resp.CollectionTranslationID = saved.CollectionTranslationID
 		}
-		_, err = t.translatorRepository.SaveToCollectionLexicalItem(ctx, collectionID, translationID)
-		if err != nil {
-			t.logger.Error("SaveToCollectionLexicalItem failed", slog.Any("err", err.Error()))
-			return
-		}
-		return
-	}
-	_, err = t.translatorRepository.SaveToCollectionLexicalItem(ctx, collectionID, translationID)
-	if err != nil {
-		t.logger.Error("SaveToCollectionLexicalItem failed", slog.Any("err", err.Error()))
-		return
 	}
-
+	return c.JSON(http.StatusOK, resp)
 }
 
-func (t TranslatorServer) callChatGPTAPI(prompt string) (*domain.Translation, error) {
-	requestBody := fmt.Sprintf(`{
-		"model": "gpt-3.5-turbo",
-		"messages": [
-			{
-				"role": "user",
-				"content": %q
-			}
-		]
-	}`, prompt)
-	req, err := http.NewRequest("POST", t.chatGPTAPIURL, bytes.NewBuffer([]byte(requestBody)))
+// lookupTranslation serves the translation from the translations table, on a miss or a forced refresh
+// it is asked from the translator and stored, so the next lookup of the same item is served from the table.
+// A forced refresh replaces the stored translation. When storing fails the translation is still returned,
+// without an ID since it can't be saved to a collection
+func (t TranslatorServer) lookupTranslation(ctx context.Context, lexicalItem, translateFrom, translateTo string, forceRefresh bool) (*domain.Translation, error) {
+	cached, err := t.translatorRepository.GetTranslation(ctx, lexicalItem, translateFrom, translateTo)
 	if err != nil {
-		return nil, err
+		// a broken cache shouldn't block the lookup, fall back to the LLM
+		t.logger.Error("failed to get cached translation", slog.Any("err", err.Error()))
+	} else if cached != nil && !forceRefresh {
+		return cached, nil
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	translation, err := t.translator.Translate(ctx, lexicalItem, translateFrom, translateTo)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if cached != nil {
+		err = t.translatorRepository.UpdateTranslation(ctx, cached.ID, *translation)
+		if err == nil {
+			translation.ID = cached.ID
+		}
+	} else {
+		translation.ID, err = t.translatorRepository.AddTranslation(ctx, *translation, translateFrom, translateTo)
 	}
-	var chatGPTResp domain.ChatGPTResponse
-	err = json.Unmarshal(body, &chatGPTResp)
 	if err != nil {
-		return nil, fmt.Errorf("could not unmarshall response: %w", err)
-	}
-	if len(chatGPTResp.Choices) != 1 {
-		return nil, fmt.Errorf("expected number of choices is 1, actual %d", len(chatGPTResp.Choices))
+		// the translation has been paid for, the caller gets it anyway
+		t.logger.Error("failed to store translation", slog.String("lexicalItem", lexicalItem), slog.Any("err", err.Error()))
+		translation.ID = 0
 	}
-	translationJSON := chatGPTResp.Choices[0].Message.Content
+	return translation, nil
+}
+
+func translationError(c echo.Context, code domain.TranslationErrorCode, message string) error {
+	status := http.StatusInternalServerError
+	switch code {
+	case domain.TranslationErrorInvalidInput, domain.TranslationErrorUnsupportedLanguage, domain.TranslationErrorLexicalItemTooLong:
+		status = http.StatusBadRequest
+	case domain.TranslationErrorInvalidTranslation:
+		status = http.StatusUnprocessableEntity
+	case domain.TranslationErrorProviderUnavailable, domain.TranslationErrorMalformedResponse:
+		status = http.StatusBadGateway
+	}
+	return c.JSON(status, domain.TranslationErrorResponse{Code: code, Message: message})
+}
 
-	var wordTranslation domain.Translation
-	err = json.Unmarshal([]byte(translationJSON), &wordTranslation)
+func (t TranslatorServer) saveToCollection(ctx context.Context, sub string, translationID int, collectionID int) (domain.SavedTranslation, error) {
+	userID, err := strconv.Atoi(sub)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding JSON: %w, received string is: %s", err, chatGPTResp.Choices[0].Message.Content)
+		return domain.SavedTranslation{}, fmt.Errorf("failed to convert sub string to userID int: %w", err)
 	}
-	return &wordTranslation, nil
+	return t.translatorRepository.SaveToCollection(ctx, userID, collectionID, translationID)
 }
 
 func (t TranslatorServer) enrichAuthToken(c echo.Context, token *domain.Token) {
@@ -332,7 +352,13 @@ func (t TranslatorServer) CreateCollection(c echo.Context) error {
 		t.logger.Error("collectoin create request - failed to convert", slog.Any("err", err.Error()))
 		return c.String(http.StatusBadRequest, "invalid input")
 	}
-	collectionID, err := t.translatorRepository.CreateCollectionByUserID(c.Request().Context(), userID, req.CollectionName)
+	if req.Scheduler == "" {
+		req.Scheduler = domain.DefaultScheduler
+	}
+	if !domain.IsSchedulerSupported(req.Scheduler) {
+		return c.String(http.StatusBadRequest, "scheduler is not supported")
+	}
+	collectionID, err := t.translatorRepository.CreateCollectionByUserID(c.Request().Context(), userID, req.CollectionName, req.Scheduler)
 	if err != nil {
 		t.logger.Error("failed to create collection for the user", slog.Any("err", err.Error()))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to create collection for the user"})
@@ -342,11 +368,6 @@ func (t TranslatorServer) CreateCollection(c echo.Context) error {
 }
 
 func (t TranslatorServer) TextToSpeech(c echo.Context) error {
-	const (
-		english = "77bbc6a8-9660-4e8a-92f1-e021f4f80cc9"
-		polish  = "346e8979-02bd-4cc9-9e15-7929da7c6ac1"
-		russian = "acdbd9ef-a79b-429f-b8ed-78a80c61d459"
-	)
 	_, failed, status := t.GetSubFromToken(c)
 	if failed {
 		return status
@@ -362,49 +383,18 @@ func (t TranslatorServer) TextToSpeech(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "text is required")
 	}
 
-	ttsPayload := map[string]string{
-		"text":     req.Text,
-		"model_id": "papla_p1",
-	}
-
-	jsonData, err := json.Marshal(ttsPayload)
-	if err != nil {
-		t.logger.Error("TTS request - failed to marshal payload", slog.Any("err", err.Error()))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal error"})
-	}
-
-	const ttsEndpointFmt = "https://api.papla.media/v1/text-to-speech/%s/stream"
-
-	var ttsEndpoint string
-	if req.Language == domain.LanguagePolish {
-		ttsEndpoint = fmt.Sprintf(ttsEndpointFmt, polish)
-	} else if req.Language == domain.LanguageRussian {
-		ttsEndpoint = fmt.Sprintf(ttsEndpointFmt, russian)
-	} else {
-		ttsEndpoint = fmt.Sprintf(ttsEndpointFmt, english)
-	}
-
-	httpReq, err := http.NewRequest("POST", ttsEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		t.logger.Error("TTS request - failed to create HTTP request", slog.Any("err", err.Error()))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal error"})
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("papla-api-key", t.ttsAPIKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	audio, err := t.tts.Stream(c.Request().Context(), req.Text, req.Language)
 	if err != nil {
 		t.logger.Error("TTS request - failed to fetch audio", slog.Any("err", err.Error()))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to fetch audio"})
+		return c.JSON(upstreamStatus(c, err), map[string]string{"message": "failed to fetch audio"})
 	}
-	defer resp.Body.Close()
+	defer audio.Close()
 
 	// Set audio headers and stream the response
 	c.Response().Header().Set("Content-Type", "audio/mpeg")
 	c.Response().Header().Set("Content-Disposition", "inline; filename=audio.mp3")
 	c.Response().WriteHeader(http.StatusOK)
-	_, copyErr := io.Copy(c.Response(), resp.Body)
+	_, copyErr := io.Copy(c.Response(), audio)
 	if copyErr != nil {
 		t.logger.Error("TTS request - failed to stream audio", slog.Any("err", copyErr.Error()))
 	}
@@ -456,28 +446,99 @@ func (t TranslatorServer) GetCollectionsTranslations(c echo.Context) error {
 		})
 	}
 
-	// Get TranslationIDs from path parameter
-	var translationIDs []int
-	translationIDsParam := c.Param("translationIDs")
-	if translationIDsParam != "" {
-		translationIDStrings := strings.Split(translationIDsParam, ",")
-		for _, idStr := range translationIDStrings {
-			id, err := strconv.Atoi(idStr)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "Invalid TranslationIDs",
-				})
-			}
-			translationIDs = append(translationIDs, id)
-		}
+	req, err := collectionTranslationsRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
+	req.CollectionID = collectionID
+	req.UserID = userID
 
-	collectionsTranslations, err := t.translatorRepository.GetCollectionTranslations(c.Request().Context(), collectionID, translationIDs, userID)
+	page, err := t.translatorRepository.GetCollectionTranslations(c.Request().Context(), req)
 	if err != nil {
 		t.logger.Error("failed to get collection's translations", slog.Any("err", err.Error()))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get collection's translations"})
 	}
-	return c.JSON(http.StatusOK, collectionsTranslations)
+	if req.Limit == 0 {
+		// the clients that don't page get the array of all the translations as before
+		return c.JSON(http.StatusOK, page.Items)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// collectionTranslationsRequest reads the filters, the sort and the page of the translations from the query params,
+// the translations are only paged when a limit or a cursor is given
+func collectionTranslationsRequest(c echo.Context) (domain.GetCollectionsTranslationsRequest, error) {
+	req := domain.GetCollectionsTranslationsRequest{
+		TranslatedFrom: strings.ToLower(strings.TrimSpace(c.QueryParam("from"))),
+		TranslatedTo:   strings.ToLower(strings.TrimSpace(c.QueryParam("to"))),
+		DueState:       c.QueryParam("due"),
+		Tag:            strings.TrimSpace(c.QueryParam("tag")),
+		Sort:           c.QueryParam("sort"),
+		Order:          c.QueryParam("order"),
+		Now:            time.Now(),
+	}
+	// the ids are given either as repeated params or separated with commas
+	for _, param := range c.QueryParams()["translationIds"] {
+		for _, idStr := range splitList(param) {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return req, errors.New("Invalid TranslationIDs")
+			}
+			req.TranslationIDs = append(req.TranslationIDs, id)
+		}
+	}
+	for _, language := range []string{req.TranslatedFrom, req.TranslatedTo} {
+		if _, ok := domain.SupportedLanguages[language]; language != "" && !ok {
+			return req, fmt.Errorf("language %q is not supported", language)
+		}
+	}
+	if req.DueState != "" && !domain.IsDueStateSupported(req.DueState) {
+		return req, fmt.Errorf("due must be one of %s, %s, %s", domain.DueStateNew, domain.DueStateDue, domain.DueStateNotDue)
+	}
+	if since := c.QueryParam("addedSince"); since != "" {
+		date, err := domain.ParseDateBound(since, false)
+		if err != nil {
+			return req, fmt.Errorf("addedSince: %w", err)
+		}
+		req.AddedSince = &date
+	}
+	if until := c.QueryParam("addedUntil"); until != "" {
+		date, err := domain.ParseDateBound(until, true)
+		if err != nil {
+			return req, fmt.Errorf("addedUntil: %w", err)
+		}
+		req.AddedUntil = &date
+	}
+	if req.Sort == "" {
+		req.Sort = domain.SortCreated
+	}
+	if !domain.IsTranslationsSortSupported(req.Sort) {
+		return req, fmt.Errorf("sort must be one of %s, %s, %s", domain.SortCreated, domain.SortDue, domain.SortAlphabetical)
+	}
+	if req.Order == "" {
+		req.Order = domain.DefaultOrder(req.Sort)
+	}
+	if req.Order != domain.OrderAsc && req.Order != domain.OrderDesc {
+		return req, fmt.Errorf("order must be %s or %s", domain.OrderAsc, domain.OrderDesc)
+	}
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > domain.MaxTranslationsPageSize {
+			return req, fmt.Errorf("limit must be between 1 and %d", domain.MaxTranslationsPageSize)
+		}
+		req.Limit = limit
+	}
+	if cursorParam := c.QueryParam("cursor"); cursorParam != "" {
+		cursor, err := domain.DecodeTranslationsCursor(cursorParam, req.Sort, req.Order)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = &cursor
+		if req.Limit == 0 {
+			req.Limit = domain.DefaultTranslationsPageSize
+		}
+	}
+	return req, nil
 }
 
 func (t TranslatorServer) DeleteCollectionsTranslations(c echo.Context) error {
@@ -529,6 +590,24 @@ func (t TranslatorServer) ExportCollectionsTranslations(c echo.Context) error {
 	if product == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "product is not specified"})
 	}
+	exporter, ok := t.exporters[product]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":     "product is not supported",
+			"supported": t.exporters.Products(),
+		})
+	}
+	options := export.Options{
+		Fields:    splitList(c.QueryParam("fields")),
+		Separator: c.QueryParam("separator"),
+		Front:     splitList(c.QueryParam("front")),
+		Back:      splitList(c.QueryParam("back")),
+		Audio:     c.QueryParam("audio") == "true",
+		Now:       time.Now(),
+	}
+	if err := options.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 	sub, failed, status := t.GetSubFromToken(c)
 	if failed {
 		return status
@@ -538,22 +617,53 @@ func (t TranslatorServer) ExportCollectionsTranslations(c echo.Context) error {
 		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
 	}
-	collectionsTranslations, err := t.translatorRepository.GetCollectionTranslations(c.Request().Context(), collectionID, []int{}, userID)
+	ctx := c.Request().Context()
+	collections, err := t.translatorRepository.GetCollectionsByUserID(ctx, userID)
 	if err != nil {
-		t.logger.Error("failed to get collection's translations", slog.Any("err", err.Error()))
+		t.logger.Error("failed to get collections", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get collection's translations"})
+	}
+	var name string
+	for _, collection := range collections {
+		if collection.ID == collectionID {
+			name = collection.Name
+		}
+	}
+	if name == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	cards, err := t.translatorRepository.GetCollectionCards(ctx, collectionID, userID)
+	if err != nil {
+		t.logger.Error("failed to get collection cards", slog.Any("err", err.Error()))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get collection's translations"})
 	}
-	var translations []domain.Translation
-	for _, collectionTranslation := range collectionsTranslations {
-		translations = append(translations, collectionTranslation.Translation)
+	var exported bytes.Buffer
+	err = exporter.Export(ctx, &exported, export.Collection{Name: name, Cards: cards}, options)
+	if err != nil {
+		t.logger.Error("failed to export collection", slog.String("product", product), slog.Any("err", err.Error()))
+		status := http.StatusInternalServerError
+		var upstreamErr *domain.UpstreamError
+		if errors.As(err, &upstreamErr) || errors.Is(err, domain.ErrCircuitOpen) {
+			// the audio could not be fetched
+			status = upstreamStatus(c, err)
+		}
+		return c.JSON(status, map[string]string{"message": "failed to export collection"})
 	}
-	quizletString := domain.ConvertTranslationToQuizletString(translations)
 
-	filename := fmt.Sprintf("flesh-cards-%s-%d.txt", product, collectionID)
+	filename := fmt.Sprintf("flesh-cards-%s-%d.%s", product, collectionID, exporter.Extension())
 	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename="+filename)
-	c.Response().Header().Set(echo.HeaderContentType, "text/plain")
+	return c.Blob(http.StatusOK, exporter.ContentType(), exported.Bytes())
+}
 
-	return c.Blob(http.StatusOK, "text/plain", []byte(quizletString))
+// splitList splits a comma separated param, empty items are dropped
+func splitList(param string) []string {
+	var values []string
+	for _, value := range strings.Split(param, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 func (t TranslatorServer) GetDueCollectionTranslation(c echo.Context) error {
@@ -571,7 +681,13 @@ func (t TranslatorServer) GetDueCollectionTranslation(c echo.Context) error {
 		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
 	}
-	translations, err := t.translatorRepository.GetDueCollectionTranslations(c.Request().Context(), collectionID, []int{}, userID)
+	ctx := c.Request().Context()
+	settings, err := t.translatorRepository.GetAccountSettings(ctx, userID)
+	if err != nil {
+		t.logger.Error("failed to get account settings", slog.Any("err", err.Error()))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	translations, err := t.translatorRepository.GetDueCollectionTranslations(ctx, collectionID, []int{}, userID, settings.DayStart(time.Now()))
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
@@ -580,6 +696,7 @@ func (t TranslatorServer) GetDueCollectionTranslation(c echo.Context) error {
 	}
 	// Random selection (optional, or just return all due)
 	idx := rand.Intn(len(translations))
+	withCloze(&translations[idx])
 	return c.JSON(http.StatusOK, translations[idx])
 }
 
@@ -608,30 +725,182 @@ func (t TranslatorServer) RateCollectionTranslation(c echo.Context) error {
 	if err := c.Bind(&input); err != nil {
 		return c.String(http.StatusBadRequest, "Invalid input")
 	}
-	switch input.Rating {
-	case domain.RatingAgain, domain.RatingWeek, domain.RatingMonth, domain.RatingTwoMonth:
-	default:
+	grade := input.Grade
+	if grade == 0 {
+		grade = input.Rating.Grade()
+	}
+	if !grade.IsValid() {
 		return c.String(http.StatusBadRequest, "Invalid rating value")
 	}
 
-	now := time.Now()
-	var newDue time.Time
-	switch input.Rating {
-	case domain.RatingAgain:
-		newDue = now
-	case domain.RatingWeek:
-		newDue = now.AddDate(0, 0, 7)
-	case domain.RatingMonth:
-		newDue = now.AddDate(0, 1, 0)
-	case domain.RatingTwoMonth:
-		newDue = now.AddDate(0, 2, 0)
+	card, err := t.reviewCard(c.Request().Context(), id, collectionID, userID, grade, input.DurationMs, time.Now().UTC())
+	if errors.Is(err, domain.ErrCollectionTranslationNotFound) {
+		return c.String(http.StatusNotFound, "collection translation not found")
 	}
-
-	if err := t.translatorRepository.UpdateCollectionTranslationDue(c.Request().Context(), id, collectionID, newDue, userID); err != nil {
+	if errors.Is(err, domain.ErrCardNotReviewable) {
+		return c.String(http.StatusConflict, "card is suspended or buried")
+	}
+	if errors.Is(err, domain.ErrReviewConflict) {
+		return c.String(http.StatusConflict, "card has been reviewed in the meantime")
+	}
+	if err != nil {
+		t.logger.Error("failed to review collection translation", slog.Any("err", err.Error()))
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.NoContent(http.StatusOK)
+	return c.JSON(http.StatusOK, card)
+}
+
+func (t TranslatorServer) UpdateReviewSettings(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	var settings domain.ReviewSettingsUpdate
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	if err := validateReviewSettings(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	err = t.translatorRepository.UpdateReviewSettings(c.Request().Context(), collectionID, userID, settings)
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to update review settings", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to update review settings"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UpdateCollection renames the collection and changes its details and review settings, it responds with the updated collection
+func (t TranslatorServer) UpdateCollection(c echo.Context) error {
+	sub, failed, status := t.GetSubFromToken(c)
+	if failed {
+		return status
+	}
+	userID, err := strconv.Atoi(sub)
+	if err != nil {
+		t.logger.Error("failed to convert sub string to userID int", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid userID"})
+	}
+	collectionID, err := strconv.Atoi(c.Param("collectionID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid CollectionID"})
+	}
+	var update domain.CollectionUpdate
+	if err := c.Bind(&update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid input"})
+	}
+	if err := validateCollectionUpdate(&update); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	ctx := c.Request().Context()
+	err = t.translatorRepository.UpdateCollection(ctx, collectionID, userID, update)
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to update collection", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to update collection"})
+	}
+	collection, err := t.translatorRepository.GetCollectionByID(ctx, collectionID, userID)
+	if errors.Is(err, domain.ErrCollectionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "collection not found"})
+	}
+	if err != nil {
+		t.logger.Error("failed to get collection", slog.Any("err", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "failed to get updated collection"})
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+// validateCollectionUpdate checks the set fields of the update and trims them
+func validateCollectionUpdate(update *domain.CollectionUpdate) error {
+	if update.Name != nil {
+		*update.Name = strings.TrimSpace(*update.Name)
+		if *update.Name == "" || utf8.RuneCountInString(*update.Name) > domain.MaxCollectionNameLength {
+			return fmt.Errorf("name must be between 1 and %d characters", domain.MaxCollectionNameLength)
+		}
+	}
+	if update.Description != nil {
+		*update.Description = strings.TrimSpace(*update.Description)
+		if utf8.RuneCountInString(*update.Description) > domain.MaxCollectionDescriptionLength {
+			return fmt.Errorf("description must be up to %d characters", domain.MaxCollectionDescriptionLength)
+		}
+	}
+	if (update.DefaultTranslatedFrom == nil) != (update.DefaultTranslatedTo == nil) {
+		return errors.New("defaultTranslatedFrom and defaultTranslatedTo must be set together")
+	}
+	if update.DefaultTranslatedFrom != nil {
+		from := strings.ToLower(strings.TrimSpace(*update.DefaultTranslatedFrom))
+		to := strings.ToLower(strings.TrimSpace(*update.DefaultTranslatedTo))
+		*update.DefaultTranslatedFrom, *update.DefaultTranslatedTo = from, to
+		if from != "" || to != "" {
+			if _, ok := domain.SupportedLanguages[from]; !ok {
+				return fmt.Errorf("language %q is not supported", from)
+			}
+			if _, ok := domain.SupportedLanguages[to]; !ok {
+				return fmt.Errorf("language %q is not supported", to)
+			}
+			if from == to {
+				return errors.New("defaultTranslatedFrom and defaultTranslatedTo must differ")
+			}
+		}
+	}
+	if update.Color != nil {
+		*update.Color = strings.ToLower(strings.TrimSpace(*update.Color))
+		if *update.Color != "" && !domain.IsColorValid(*update.Color) {
+			return errors.New("color must be a hex color, e.g. #1e90ff")
+		}
+	}
+	if update.Icon != nil {
+		*update.Icon = strings.TrimSpace(*update.Icon)
+		if utf8.RuneCountInString(*update.Icon) > domain.MaxCollectionIconLength {
+			return fmt.Errorf("icon must be up to %d characters", domain.MaxCollectionIconLength)
+		}
+	}
+	if update.ReviewSettings != nil {
+		return validateReviewSettings(update.ReviewSettings)
+	}
+	return nil
+}
+
+// validateReviewSettings checks the set fields of the update, the card templates are normalized
+func validateReviewSettings(settings *domain.ReviewSettingsUpdate) error {
+	if settings.Scheduler != nil && !domain.IsSchedulerSupported(*settings.Scheduler) {
+		return errors.New("scheduler is not supported")
+	}
+	if settings.NewCardsPerDay != nil && !domain.IsCardsPerDayValid(*settings.NewCardsPerDay) {
+		return errors.New("newCardsPerDay must be between 0 and " + strconv.Itoa(domain.MaxCardsPerDay))
+	}
+	if settings.ReviewsPerDay != nil && !domain.IsCardsPerDayValid(*settings.ReviewsPerDay) {
+		return errors.New("reviewsPerDay must be between 0 and " + strconv.Itoa(domain.MaxCardsPerDay))
+	}
+	if settings.LeechThreshold != nil && !domain.IsLeechThresholdValid(*settings.LeechThreshold) {
+		return errors.New("leechThreshold must be between 0 and " + strconv.Itoa(domain.MaxLeechThreshold))
+	}
+	if settings.LeechAction != nil && !domain.IsLeechActionSupported(*settings.LeechAction) {
+		return errors.New("leech action is not supported")
+	}
+	if settings.CardTemplates != nil {
+		templates, ok := domain.NormalizeCardTemplates(settings.CardTemplates)
+		if !ok {
+			return errors.New("cardTemplates must list supported templates")
+		}
+		settings.CardTemplates = templates
+	}
+	return nil
 }
 
 func (t TranslatorServer) GetSubFromToken(c echo.Context) (string, bool, error) {
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// upstreamStatus maps a failed call to a third party API to the status returned to the client
+func upstreamStatus(c echo.Context, err error) int {
+	if errors.Is(err, domain.ErrCircuitOpen) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	var upstreamErr *domain.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return http.StatusBadGateway
+	}
+	switch upstreamErr.StatusCode {
+	case http.StatusTooManyRequests:
+		if upstreamErr.RetryAfter > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(upstreamErr.RetryAfter.Seconds())))
+		}
+		return http.StatusTooManyRequests
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		// the input sent by our client was rejected
+		return upstreamErr.StatusCode
+	default:
+		// auth problems and upstream failures are ours, not the client's
+		return http.StatusBadGateway
+	}
+}
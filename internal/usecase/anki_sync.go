@@ -0,0 +1,330 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/anki"
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/scheduler"
+)
+
+// AnkiConnectClient manages the notes and the cards of an Anki collection through the AnkiConnect add-on
+type AnkiConnectClient interface {
+	CreateDeck(ctx context.Context, conn domain.AnkiConnection, deck string) error
+	EnsureNoteType(ctx context.Context, conn domain.AnkiConnection) error
+	AddNotes(ctx context.Context, conn domain.AnkiConnection, notes []domain.AnkiNote) ([]int64, error)
+	FindNote(ctx context.Context, conn domain.AnkiConnection, note domain.AnkiNote) (int64, error)
+	FindCards(ctx context.Context, conn domain.AnkiConnection, noteIDs []int64) ([]domain.AnkiCardInfo, error)
+	CardsInfo(ctx context.Context, conn domain.AnkiConnection, cardIDs []int64) ([]domain.AnkiCardInfo, error)
+	GetReviews(ctx context.Context, conn domain.AnkiConnection, cardIDs []int64) (map[int64][]domain.AnkiReview, error)
+	Reschedule(ctx context.Context, conn domain.AnkiConnection, cardID int64, days int, ease float64) error
+}
+
+// AnkiSyncRepository keeps the Anki notes and cards of the collections
+type AnkiSyncRepository interface {
+	GetAnkiTarget(ctx context.Context, userID, collectionID int) (domain.AnkiTarget, error)
+	SetAnkiDeck(ctx context.Context, userID, collectionID int, deck string) error
+	GetCollectionCards(ctx context.Context, collectionID int, userID int) ([]domain.CollectionTranslation, error)
+	GetAnkiNotes(ctx context.Context, collectionID int) (map[int]int64, error)
+	SaveAnkiNote(ctx context.Context, collectionID, translationID int, noteID int64) error
+	GetAnkiCards(ctx context.Context, collectionID int) ([]domain.AnkiCardLink, error)
+	SaveAnkiCard(ctx context.Context, link domain.AnkiCardLink) error
+	SaveSyncedReviews(ctx context.Context, card domain.CollectionTranslation, userID int, reviews []domain.ReviewLog) error
+}
+
+// AnkiSync syncs a collection with a deck of Anki both ways
+type AnkiSync struct {
+	repository AnkiSyncRepository
+	client     AnkiConnectClient
+}
+
+func NewAnkiSync(repository AnkiSyncRepository, client AnkiConnectClient) *AnkiSync {
+	return &AnkiSync{repository: repository, client: client}
+}
+
+// Sync pushes the new translations of the collection to its Anki deck as notes and reconciles the schedule
+// of every card with its Anki card: the side that was reviewed last wins. The reviews made in Anki since
+// the last review here are replayed into the review log, and a card reviewed here last is rescheduled in Anki.
+// The deck is named after the collection on the first sync. Notes deleted on either side aren't deleted
+// on the other one, and everything is stored as soon as it is synced so a failed sync is resumed by the next one
+func (a AnkiSync) Sync(ctx context.Context, userID, collectionID int, now time.Time) (domain.AnkiSyncResult, error) {
+	var result domain.AnkiSyncResult
+	target, err := a.repository.GetAnkiTarget(ctx, userID, collectionID)
+	if err != nil {
+		return result, err
+	}
+	if target.Connection.URL == "" {
+		return result, domain.ErrAnkiConnectNotConnected
+	}
+	cardScheduler, err := scheduler.New(target.Scheduler)
+	if err != nil {
+		return result, err
+	}
+	conn := target.Connection
+	deck := target.Deck
+	if deck == "" {
+		deck = target.CollectionName
+	}
+	result.Deck = deck
+	if err := a.client.CreateDeck(ctx, conn, deck); err != nil {
+		return result, err
+	}
+	if target.Deck == "" {
+		if err := a.repository.SetAnkiDeck(ctx, userID, collectionID, deck); err != nil {
+			return result, err
+		}
+	}
+	if err := a.client.EnsureNoteType(ctx, conn); err != nil {
+		return result, err
+	}
+
+	cards, err := a.repository.GetCollectionCards(ctx, collectionID, userID)
+	if err != nil {
+		return result, err
+	}
+	// the cards of the templates the note type doesn't have, e.g. cloze, stay out of Anki
+	cards = slices.DeleteFunc(cards, func(card domain.CollectionTranslation) bool {
+		return anki.TemplateOrd(card.Template) < 0
+	})
+	notes, err := a.pushNotes(ctx, conn, deck, collectionID, cards, &result)
+	if err != nil {
+		return result, err
+	}
+	links, err := a.linkCards(ctx, conn, collectionID, cards, notes, &result)
+	if err != nil {
+		return result, err
+	}
+	if len(links) == 0 {
+		return result, nil
+	}
+
+	cardIDs := make([]int64, 0, len(links))
+	for _, link := range links {
+		cardIDs = append(cardIDs, link.CardID)
+	}
+	infos, err := a.client.CardsInfo(ctx, conn, cardIDs)
+	if err != nil {
+		return result, err
+	}
+	reviews, err := a.client.GetReviews(ctx, conn, cardIDs)
+	if err != nil {
+		return result, err
+	}
+	remote := make(map[int64]domain.AnkiCardInfo, len(infos))
+	for _, info := range infos {
+		remote[info.CardID] = info
+	}
+
+	for _, card := range cards {
+		link, ok := links[card.ID]
+		if !ok {
+			continue
+		}
+		info, ok := remote[link.CardID]
+		if !ok {
+			// the card was deleted in Anki
+			continue
+		}
+		if err := a.reconcile(ctx, conn, cardScheduler, userID, card, link, info, reviews[link.CardID], now, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// pushNotes adds a note for every translation of the cards that has none and returns the ids of the notes
+// by the ids of their translations. A note Anki refuses as a duplicate is taken from the deck
+func (a AnkiSync) pushNotes(
+	ctx context.Context,
+	conn domain.AnkiConnection,
+	deck string,
+	collectionID int,
+	cards []domain.CollectionTranslation,
+	result *domain.AnkiSyncResult,
+) (map[int]int64, error) {
+	notes, err := a.repository.GetAnkiNotes(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	var newNotes []domain.AnkiNote
+	var translationIDs []int
+	for _, card := range cards {
+		if _, ok := notes[card.Translation.ID]; ok || slices.Contains(translationIDs, card.Translation.ID) {
+			continue
+		}
+		translationIDs = append(translationIDs, card.Translation.ID)
+		newNotes = append(newNotes, domain.AnkiNote{
+			Deck:   deck,
+			Model:  anki.ModelName,
+			Fields: anki.NoteFields(card.Translation),
+			Tags:   noteTags(cards, card.Translation.ID),
+		})
+	}
+	if len(newNotes) == 0 {
+		return notes, nil
+	}
+	noteIDs, err := a.client.AddNotes(ctx, conn, newNotes)
+	if err != nil {
+		return nil, err
+	}
+	for i, noteID := range noteIDs {
+		if noteID != 0 {
+			result.NotesAdded++
+		} else if noteID, err = a.client.FindNote(ctx, conn, newNotes[i]); err != nil {
+			return nil, err
+		}
+		if noteID == 0 {
+			continue
+		}
+		if err := a.repository.SaveAnkiNote(ctx, collectionID, translationIDs[i], noteID); err != nil {
+			return nil, err
+		}
+		notes[translationIDs[i]] = noteID
+	}
+	return notes, nil
+}
+
+// linkCards links the cards that aren't linked yet to the Anki cards of their notes with the same template
+// and returns the links by the ids of the cards
+func (a AnkiSync) linkCards(
+	ctx context.Context,
+	conn domain.AnkiConnection,
+	collectionID int,
+	cards []domain.CollectionTranslation,
+	notes map[int]int64,
+	result *domain.AnkiSyncResult,
+) (map[int]domain.AnkiCardLink, error) {
+	saved, err := a.repository.GetAnkiCards(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[int]domain.AnkiCardLink, len(saved))
+	for _, link := range saved {
+		links[link.CollectionTranslationID] = link
+	}
+	var noteIDs []int64
+	for _, card := range cards {
+		noteID, ok := notes[card.Translation.ID]
+		if _, linked := links[card.ID]; ok && !linked && !slices.Contains(noteIDs, noteID) {
+			noteIDs = append(noteIDs, noteID)
+		}
+	}
+	if len(noteIDs) == 0 {
+		return links, nil
+	}
+	infos, err := a.client.FindCards(ctx, conn, noteIDs)
+	if err != nil {
+		return nil, err
+	}
+	type noteCard struct {
+		noteID int64
+		ord    int
+	}
+	ankiCards := make(map[noteCard]int64, len(infos))
+	for _, info := range infos {
+		ankiCards[noteCard{noteID: info.NoteID, ord: info.Ord}] = info.CardID
+	}
+	for _, card := range cards {
+		if _, linked := links[card.ID]; linked {
+			continue
+		}
+		cardID, ok := ankiCards[noteCard{noteID: notes[card.Translation.ID], ord: anki.TemplateOrd(card.Template)}]
+		if !ok {
+			continue
+		}
+		link := domain.AnkiCardLink{CollectionTranslationID: card.ID, CardID: cardID}
+		if err := a.repository.SaveAnkiCard(ctx, link); err != nil {
+			return nil, err
+		}
+		links[card.ID] = link
+		result.CardsLinked++
+	}
+	return links, nil
+}
+
+// reconcile brings the card and its Anki card to the schedule of the side reviewed last, the reviews pulled
+// from Anki take their schedule from Anki and their memory state from cardScheduler
+func (a AnkiSync) reconcile(
+	ctx context.Context,
+	conn domain.AnkiConnection,
+	cardScheduler scheduler.Scheduler,
+	userID int,
+	card domain.CollectionTranslation,
+	link domain.AnkiCardLink,
+	info domain.AnkiCardInfo,
+	log []domain.AnkiReview,
+	now time.Time,
+	result *domain.AnkiSyncResult,
+) error {
+	var answers []domain.AnkiReview
+	for _, review := range log {
+		if review.IsAnswer() && (card.LastReview == nil || review.ReviewedAt().After(*card.LastReview)) {
+			answers = append(answers, review)
+		}
+	}
+	if len(answers) > 0 {
+		state := card.CardState
+		reviewLogs := make([]domain.ReviewLog, 0, len(answers))
+		for _, review := range answers {
+			next := review.Apply(state)
+			replayed := cardScheduler.Schedule(state, domain.Grade(review.Ease), review.ReviewedAt())
+			next.Stability, next.Difficulty = replayed.Stability, replayed.Difficulty
+			durationMs := review.Time
+			reviewLogs = append(reviewLogs, domain.NewReviewLog(card.ID, userID, domain.Grade(review.Ease), state, next, &durationMs, review.ReviewedAt()))
+			state = next
+		}
+		// Anki counts the reviews made before the card was linked too
+		state.Reps, state.Lapses = info.Reps, info.Lapses
+		card.CardState = state
+		if err := a.repository.SaveSyncedReviews(ctx, card, userID, reviewLogs); err != nil {
+			return err
+		}
+		// the schedule came from Anki, there is nothing to push back
+		link.PushedReview = state.LastReview
+		if err := a.repository.SaveAnkiCard(ctx, link); err != nil {
+			return err
+		}
+		result.CardsPulled++
+		result.ReviewsPulled += len(answers)
+		return nil
+	}
+
+	if card.LastReview == nil || card.Due == nil || (link.PushedReview != nil && link.PushedReview.Equal(*card.LastReview)) {
+		result.Unchanged++
+		return nil
+	}
+	days := int(math.Floor(card.Due.Sub(now).Hours() / 24))
+	if days < 0 {
+		days = 0
+	}
+	if err := a.client.Reschedule(ctx, conn, link.CardID, days, card.Ease); err != nil {
+		return err
+	}
+	link.PushedReview = card.LastReview
+	if err := a.repository.SaveAnkiCard(ctx, link); err != nil {
+		return err
+	}
+	result.CardsPushed++
+	return nil
+}
+
+// noteTags are the tags of the cards of the translation, Anki separates tags with spaces
+func noteTags(cards []domain.CollectionTranslation, translationID int) []string {
+	var tags []string
+	for _, card := range cards {
+		if card.Translation.ID != translationID {
+			continue
+		}
+		for _, tag := range card.Tags {
+			tag = strings.ReplaceAll(tag, " ", "_")
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+	"github.com/bukhavtsov/artems-dictionary/internal/infrastructure"
+	"github.com/bukhavtsov/artems-dictionary/internal/scheduler"
+)
+
+// fakeAnki is an AnkiConnect add-on keeping a single deck in memory, every note has a forward and a reverse card
+type fakeAnki struct {
+	mu       sync.Mutex
+	nextID   int64
+	notes    []int64
+	cards    []domain.AnkiCardInfo
+	reviews  map[int64][]domain.AnkiReview
+	dueDates map[int64]string
+	eases    map[int64]int
+}
+
+func newFakeAnki(t *testing.T) (*fakeAnki, domain.AnkiConnection) {
+	t.Helper()
+	f := &fakeAnki{nextID: 1000, reviews: map[int64][]domain.AnkiReview{}, dueDates: map[int64]string{}, eases: map[int64]int{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, domain.AnkiConnection{URL: server.URL}
+}
+
+func (f *fakeAnki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result, err := f.call(req.Action, req.Params)
+	reply := map[string]any{"result": result, "error": nil}
+	if err != "" {
+		reply["error"] = err
+	}
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+func (f *fakeAnki) call(action string, raw json.RawMessage) (any, string) {
+	var params struct {
+		Notes       []json.RawMessage `json:"notes"`
+		Query       string            `json:"query"`
+		Cards       []json.Number     `json:"cards"`
+		Days        string            `json:"days"`
+		EaseFactors []int             `json:"easeFactors"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, err.Error()
+		}
+	}
+	switch action {
+	case "createDeck":
+		return 1, ""
+	case "modelNames":
+		return []string{"Basic", "Smart Dictionary"}, ""
+	case "addNotes":
+		ids := make([]int64, 0, len(params.Notes))
+		for range params.Notes {
+			noteID := f.id()
+			f.notes = append(f.notes, noteID)
+			for ord := 0; ord < 2; ord++ {
+				f.cards = append(f.cards, domain.AnkiCardInfo{CardID: f.id(), NoteID: noteID, Ord: ord})
+			}
+			ids = append(ids, noteID)
+		}
+		return ids, ""
+	case "findNotes":
+		return []int64{}, ""
+	case "findCards":
+		var ids []int64
+		for _, card := range f.cards {
+			for _, noteID := range strings.Split(strings.TrimPrefix(params.Query, "nid:"), ",") {
+				if noteID == strconv.FormatInt(card.NoteID, 10) {
+					ids = append(ids, card.CardID)
+				}
+			}
+		}
+		return ids, ""
+	case "cardsInfo":
+		infos := make([]domain.AnkiCardInfo, 0, len(params.Cards))
+		for _, id := range params.Cards {
+			if card, ok := f.card(id); ok {
+				infos = append(infos, card)
+			}
+		}
+		return infos, ""
+	case "getReviewsOfCards":
+		reviews := make(map[string][]domain.AnkiReview, len(params.Cards))
+		for _, id := range params.Cards {
+			if card, ok := f.card(id); ok {
+				reviews[id.String()] = append([]domain.AnkiReview{}, f.reviews[card.CardID]...)
+			}
+		}
+		return reviews, ""
+	case "setDueDate":
+		for _, id := range params.Cards {
+			cardID, _ := id.Int64()
+			f.dueDates[cardID] = params.Days
+		}
+		return true, ""
+	case "setEaseFactors":
+		for i, id := range params.Cards {
+			cardID, _ := id.Int64()
+			f.eases[cardID] = params.EaseFactors[i]
+		}
+		return []bool{true}, ""
+	}
+	return nil, "unsupported action " + action
+}
+
+func (f *fakeAnki) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+func (f *fakeAnki) card(id json.Number) (domain.AnkiCardInfo, bool) {
+	for _, card := range f.cards {
+		if id.String() == strconv.FormatInt(card.CardID, 10) {
+			return card, true
+		}
+	}
+	return domain.AnkiCardInfo{}, false
+}
+
+// review answers the card in Anki
+func (f *fakeAnki) review(cardID int64, review domain.AnkiReview) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reviews[cardID] = append(f.reviews[cardID], review)
+	for i := range f.cards {
+		if f.cards[i].CardID == cardID {
+			f.cards[i].Reps++
+			if review.Ease == int(domain.GradeAgain) && review.Type == domain.AnkiReviewReview {
+				f.cards[i].Lapses++
+			}
+		}
+	}
+}
+
+// fakeAnkiSyncRepository keeps the cards of a single collection in memory
+type fakeAnkiSyncRepository struct {
+	target  domain.AnkiTarget
+	cards   []domain.CollectionTranslation
+	notes   map[int]int64
+	links   map[int]domain.AnkiCardLink
+	reviews []domain.ReviewLog
+}
+
+func (r *fakeAnkiSyncRepository) GetAnkiTarget(context.Context, int, int) (domain.AnkiTarget, error) {
+	return r.target, nil
+}
+
+func (r *fakeAnkiSyncRepository) SetAnkiDeck(_ context.Context, _, _ int, deck string) error {
+	r.target.Deck = deck
+	return nil
+}
+
+func (r *fakeAnkiSyncRepository) GetCollectionCards(context.Context, int, int) ([]domain.CollectionTranslation, error) {
+	return append([]domain.CollectionTranslation{}, r.cards...), nil
+}
+
+func (r *fakeAnkiSyncRepository) GetAnkiNotes(context.Context, int) (map[int]int64, error) {
+	notes := make(map[int]int64, len(r.notes))
+	for translationID, noteID := range r.notes {
+		notes[translationID] = noteID
+	}
+	return notes, nil
+}
+
+func (r *fakeAnkiSyncRepository) SaveAnkiNote(_ context.Context, _, translationID int, noteID int64) error {
+	r.notes[translationID] = noteID
+	return nil
+}
+
+func (r *fakeAnkiSyncRepository) GetAnkiCards(context.Context, int) ([]domain.AnkiCardLink, error) {
+	links := make([]domain.AnkiCardLink, 0, len(r.links))
+	for _, link := range r.links {
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func (r *fakeAnkiSyncRepository) SaveAnkiCard(_ context.Context, link domain.AnkiCardLink) error {
+	r.links[link.CollectionTranslationID] = link
+	return nil
+}
+
+func (r *fakeAnkiSyncRepository) SaveSyncedReviews(_ context.Context, card domain.CollectionTranslation, _ int, reviews []domain.ReviewLog) error {
+	for i := range r.cards {
+		if r.cards[i].ID == card.ID {
+			r.cards[i] = card
+		}
+	}
+	r.reviews = append(r.reviews, reviews...)
+	return nil
+}
+
+func newTestAnkiSync(t *testing.T, now time.Time) (*AnkiSync, *fakeAnki, *fakeAnkiSyncRepository) {
+	t.Helper()
+	fake, conn := newFakeAnki(t)
+	lastReview := now.Add(-24 * time.Hour)
+	due := now.Add(72 * time.Hour)
+	translation := domain.Translation{ID: 7, OriginalLexicalItem: "dog", TranslatedLexicalItem: "Hund", TranslatedFrom: "en", TranslatedTo: "de"}
+	repository := &fakeAnkiSyncRepository{
+		target: domain.AnkiTarget{Connection: conn, CollectionName: "Animals", Scheduler: domain.SchedulerFSRS},
+		cards: []domain.CollectionTranslation{
+			{
+				ID:          1,
+				Translation: translation,
+				Template:    domain.CardTemplateForward,
+				CardState:   domain.CardState{Due: &due, Ease: 2.5, IntervalDays: 3, Reps: 1, Stability: 3, Difficulty: 5, LastReview: &lastReview},
+			},
+			{ID: 2, Translation: translation, Template: domain.CardTemplateReverse},
+		},
+		notes: map[int]int64{},
+		links: map[int]domain.AnkiCardLink{},
+	}
+	httpClient := infrastructure.NewHTTPClient(infrastructure.HTTPClientConfig{Timeout: 5 * time.Second, MaxAttempts: 1})
+	return NewAnkiSync(repository, infrastructure.NewAnkiConnectClient(httpClient)), fake, repository
+}
+
+func TestAnkiSyncPushesSchedule(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ankiSync, fake, repository := newTestAnkiSync(t, now)
+
+	result, err := ankiSync.Sync(context.Background(), 1, 1, now)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	want := domain.AnkiSyncResult{Deck: "Animals", NotesAdded: 1, CardsLinked: 2, CardsPushed: 1, Unchanged: 1}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if repository.target.Deck != "Animals" {
+		t.Errorf("deck = %q, want Animals", repository.target.Deck)
+	}
+	forward := repository.links[1].CardID
+	if fake.dueDates[forward] != "3!" || fake.eases[forward] != 2500 {
+		t.Errorf("forward card was rescheduled in %q days with ease %d, want 3! and 2500", fake.dueDates[forward], fake.eases[forward])
+	}
+	if _, ok := fake.dueDates[repository.links[2].CardID]; ok {
+		t.Error("new reverse card was rescheduled")
+	}
+
+	result, err = ankiSync.Sync(context.Background(), 1, 1, now)
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if want := (domain.AnkiSyncResult{Deck: "Animals", Unchanged: 2}); result != want {
+		t.Errorf("second result = %+v, want %+v", result, want)
+	}
+}
+
+func TestAnkiSyncPullsReviews(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ankiSync, fake, repository := newTestAnkiSync(t, now)
+	if _, err := ankiSync.Sync(context.Background(), 1, 1, now); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	reviewedAt := now.Add(time.Hour)
+	prev := repository.cards[0].CardState
+	fake.review(repository.links[1].CardID, domain.AnkiReview{
+		ID:       reviewedAt.UnixMilli(),
+		Ease:     int(domain.GradeAgain),
+		Interval: -600,
+		Factor:   2300,
+		Time:     4000,
+		Type:     domain.AnkiReviewReview,
+	})
+	fake.review(repository.links[2].CardID, domain.AnkiReview{
+		ID:       reviewedAt.UnixMilli(),
+		Ease:     int(domain.GradeGood),
+		Interval: 4,
+		Factor:   2500,
+		Time:     3000,
+		Type:     domain.AnkiReviewLearn,
+	})
+
+	result, err := ankiSync.Sync(context.Background(), 1, 1, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if want := (domain.AnkiSyncResult{Deck: "Animals", CardsPulled: 2, ReviewsPulled: 2}); result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	if len(repository.reviews) != 2 {
+		t.Fatalf("%d reviews were saved, want 2", len(repository.reviews))
+	}
+
+	forward := repository.cards[0]
+	if !forward.LastReview.Equal(reviewedAt) || !forward.Due.Equal(reviewedAt.Add(10*time.Minute)) || forward.Ease != 2.3 || forward.Lapses != 1 {
+		t.Errorf("forward card = %+v, want the schedule of Anki", forward.CardState)
+	}
+	replayed := scheduler.NewFSRS().Schedule(prev, domain.GradeAgain, reviewedAt)
+	if forward.Stability != replayed.Stability || forward.Difficulty != replayed.Difficulty {
+		t.Errorf("forward card memory state = %v/%v, want %v/%v", forward.Stability, forward.Difficulty, replayed.Stability, replayed.Difficulty)
+	}
+	if forward.Stability >= prev.Stability {
+		t.Errorf("forward card stability = %v, want it lower than %v after a lapse", forward.Stability, prev.Stability)
+	}
+
+	reverse := repository.cards[1]
+	if reverse.IntervalDays != 4 || !reverse.Due.Equal(reviewedAt.AddDate(0, 0, 4)) {
+		t.Errorf("reverse card = %+v, want due in 4 days", reverse.CardState)
+	}
+	if reverse.Stability == 0 || reverse.Difficulty == 0 {
+		t.Errorf("reverse card memory state = %v/%v, want it set by FSRS", reverse.Stability, reverse.Difficulty)
+	}
+
+	// the pulled schedules aren't pushed back
+	pushed := len(fake.dueDates)
+	result, err = ankiSync.Sync(context.Background(), 1, 1, now.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("third Sync: %v", err)
+	}
+	if want := (domain.AnkiSyncResult{Deck: "Animals", Unchanged: 2}); result != want || len(fake.dueDates) != pushed {
+		t.Errorf("third result = %+v with %d reschedules, want %+v with %d", result, len(fake.dueDates), want, pushed)
+	}
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// MochiClient manages the decks and the cards of a Mochi account
+type MochiClient interface {
+	CreateDeck(ctx context.Context, token, name string) (string, error)
+	CreateCard(ctx context.Context, token, deckID, content string) (string, error)
+	UpdateCard(ctx context.Context, token, cardID, deckID, content string) error
+	DeleteCard(ctx context.Context, token, cardID string) error
+}
+
+// MochiSyncRepository keeps the Mochi cards of the collections
+type MochiSyncRepository interface {
+	GetMochiTarget(ctx context.Context, userID, collectionID int) (domain.MochiTarget, error)
+	SetMochiDeck(ctx context.Context, userID, collectionID int, deckID string) error
+	GetCollectionCards(ctx context.Context, collectionID int, userID int) ([]domain.CollectionTranslation, error)
+	GetMochiCards(ctx context.Context, collectionID int) ([]domain.MochiCard, error)
+	SaveMochiCard(ctx context.Context, card domain.MochiCard) error
+	DeleteMochiCard(ctx context.Context, collectionID, translationID int) error
+}
+
+// MochiSync pushes the translations of a collection to a Mochi deck
+type MochiSync struct {
+	repository MochiSyncRepository
+	client     MochiClient
+}
+
+func NewMochiSync(repository MochiSyncRepository, client MochiClient) *MochiSync {
+	return &MochiSync{repository: repository, client: client}
+}
+
+// Sync makes the Mochi deck of the collection mirror it: a card is created for every new translation,
+// the cards of changed translations are updated and the cards of removed ones are deleted.
+// A deck named after the collection is created when the collection isn't mapped to one.
+// Every card is stored as soon as it is pushed, so a failed sync is resumed by the next one
+func (m MochiSync) Sync(ctx context.Context, userID, collectionID int, now time.Time) (domain.MochiSyncResult, error) {
+	var result domain.MochiSyncResult
+	target, err := m.repository.GetMochiTarget(ctx, userID, collectionID)
+	if err != nil {
+		return result, err
+	}
+	if target.APIToken == "" {
+		return result, domain.ErrMochiNotConnected
+	}
+	if target.DeckID == "" {
+		target.DeckID, err = m.client.CreateDeck(ctx, target.APIToken, target.CollectionName)
+		if err != nil {
+			return result, err
+		}
+		if err := m.repository.SetMochiDeck(ctx, userID, collectionID, target.DeckID); err != nil {
+			return result, err
+		}
+	}
+	result.DeckID = target.DeckID
+
+	cards, err := m.repository.GetCollectionCards(ctx, collectionID, userID)
+	if err != nil {
+		return result, err
+	}
+	synced, err := m.repository.GetMochiCards(ctx, collectionID)
+	if err != nil {
+		return result, err
+	}
+	remote := make(map[int]domain.MochiCard, len(synced))
+	for _, card := range synced {
+		remote[card.TranslationID] = card
+	}
+
+	pushed := make(map[int]bool)
+	for _, card := range cards {
+		// a Mochi card has both sides of a translation, the cards of the templates share it
+		if pushed[card.Translation.ID] {
+			continue
+		}
+		pushed[card.Translation.ID] = true
+		content := domain.MochiContent(card.Translation)
+		mochiCard := domain.MochiCard{
+			CollectionID:  collectionID,
+			TranslationID: card.Translation.ID,
+			DeckID:        target.DeckID,
+			ContentHash:   contentHash(content),
+			SyncedAt:      now.UTC(),
+		}
+		existing, ok := remote[card.Translation.ID]
+		switch {
+		case ok && existing.ContentHash == mochiCard.ContentHash && existing.DeckID == mochiCard.DeckID:
+			result.Unchanged++
+			continue
+		case ok:
+			mochiCard.CardID = existing.CardID
+			err = m.client.UpdateCard(ctx, target.APIToken, existing.CardID, target.DeckID, content)
+			if err == nil {
+				result.Updated++
+				break
+			}
+			if !isNotFound(err) {
+				return result, err
+			}
+			// the card was deleted in Mochi, it is pushed again
+			fallthrough
+		default:
+			mochiCard.CardID, err = m.client.CreateCard(ctx, target.APIToken, target.DeckID, content)
+			if err != nil {
+				return result, err
+			}
+			result.Created++
+		}
+		if err := m.repository.SaveMochiCard(ctx, mochiCard); err != nil {
+			return result, err
+		}
+	}
+
+	for _, card := range synced {
+		if pushed[card.TranslationID] {
+			continue
+		}
+		err := m.client.DeleteCard(ctx, target.APIToken, card.CardID)
+		if err != nil && !isNotFound(err) {
+			return result, err
+		}
+		if err := m.repository.DeleteMochiCard(ctx, collectionID, card.TranslationID); err != nil {
+			return result, err
+		}
+		result.Deleted++
+	}
+	return result, nil
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func isNotFound(err error) bool {
+	var upstreamErr *domain.UpstreamError
+	return errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// Translator translates a lexical item between two supported languages
+type Translator interface {
+	Translate(ctx context.Context, lexicalItem, translateFrom, translateTo string) (*domain.Translation, error)
+}
+
+// LLMClient is implemented by every LLM provider, it returns the raw text of the model reply
+type LLMClient interface {
+	Complete(ctx context.Context, req domain.LLMRequest) (string, error)
+}
+
+const (
+	translationSystemPrompt   = "You are a bilingual dictionary. Reply with a single JSON object matching the requested schema, without code fences or commentary."
+	translationPromptTemplate = "Translate the lexical item: '%s', from '%s' to '%s'. Set translatedFrom to '%[2]s', translatedTo to '%[3]s' and originalLexicalItem to '%[1]s'. Set partOfSpeech to the part of speech of the original lexical item. Provide two examples in each language. Ensure that 'originalMeaning' is in the original language ('translatedFrom') and 'translatedMeaning' is in the target language ('translatedTo')."
+	repairPromptTemplate      = "Your reply can't be accepted: %v. Reply again with only the corrected JSON object."
+)
+
+// LLMTranslator implements Translator on top of any LLMClient
+type LLMTranslator struct {
+	client LLMClient
+}
+
+func NewLLMTranslator(client LLMClient) *LLMTranslator {
+	return &LLMTranslator{client: client}
+}
+
+// Translate asks the LLM for a translation, the reply is validated and in case it is malformed
+// the LLM gets one more chance to repair it
+func (l LLMTranslator) Translate(ctx context.Context, lexicalItem, translateFrom, translateTo string) (*domain.Translation, error) {
+	req := domain.LLMRequest{
+		System: translationSystemPrompt,
+		Messages: []domain.LLMMessage{{
+			Role:    domain.LLMRoleUser,
+			Content: fmt.Sprintf(translationPromptTemplate, lexicalItem, translateFrom, translateTo),
+		}},
+		SchemaName: domain.TranslationSchemaName,
+		Schema:     domain.TranslationSchema,
+	}
+	content, err := l.client.Complete(ctx, req)
+	if err != nil {
+		return nil, &domain.TranslationError{Code: domain.TranslationErrorProviderUnavailable, Err: err}
+	}
+	translation, err := parseTranslation(content, lexicalItem, translateFrom, translateTo)
+	if err == nil {
+		return translation, nil
+	}
+
+	req.Messages = append(req.Messages,
+		domain.LLMMessage{Role: domain.LLMRoleAssistant, Content: content},
+		domain.LLMMessage{Role: domain.LLMRoleUser, Content: fmt.Sprintf(repairPromptTemplate, err)},
+	)
+	content, err = l.client.Complete(ctx, req)
+	if err != nil {
+		return nil, &domain.TranslationError{Code: domain.TranslationErrorProviderUnavailable, Err: err}
+	}
+	return parseTranslation(content, lexicalItem, translateFrom, translateTo)
+}
+
+func parseTranslation(content, lexicalItem, translateFrom, translateTo string) (*domain.Translation, error) {
+	var translation domain.Translation
+	err := json.Unmarshal([]byte(cleanJSON(content)), &translation)
+	if err != nil {
+		return nil, &domain.TranslationError{
+			Code: domain.TranslationErrorMalformedResponse,
+			Err:  fmt.Errorf("error decoding JSON: %w, received string is: %s", err, content),
+		}
+	}
+	err = domain.ValidateTranslation(&translation, lexicalItem, translateFrom, translateTo)
+	if err != nil {
+		return nil, &domain.TranslationError{Code: domain.TranslationErrorInvalidTranslation, Err: err}
+	}
+	// the languages are validated case-insensitively, store them the way the rest of the app names them
+	translation.TranslatedFrom = translateFrom
+	translation.TranslatedTo = translateTo
+	translation.PartOfSpeech = domain.NormalizePartOfSpeech(translation.PartOfSpeech)
+	return &translation, nil
+}
+
+// cleanJSON strips markdown code fences and any commentary around the outermost JSON object
+func cleanJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		// drop the language tag, e.g. ```json
+		if newLine := strings.IndexByte(content, '\n'); newLine != -1 && !strings.Contains(content[:newLine], "{") {
+			content = content[newLine+1:]
+		}
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	start := strings.IndexByte(content, '{')
+	end := strings.LastIndexByte(content, '}')
+	if start == -1 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bukhavtsov/artems-dictionary/internal/domain"
+)
+
+// fakeLLM replies with the queued replies in order and keeps the requests it got
+type fakeLLM struct {
+	replies  []string
+	requests []domain.LLMRequest
+}
+
+func (f *fakeLLM) Complete(_ context.Context, req domain.LLMRequest) (string, error) {
+	f.requests = append(f.requests, req)
+	if len(f.requests) > len(f.replies) {
+		return "", errors.New("no reply queued")
+	}
+	return f.replies[len(f.requests)-1], nil
+}
+
+const (
+	validReply = `{"originalLexicalItem":"house","originalMeaning":"a building for people to live in",` +
+		`"originalExamples":["Our house is old.","They bought a house."],"translatedFrom":"English","translatedTo":"Russian",` +
+		`"translatedLexicalItem":"дом","translatedMeaning":"здание для жилья",` +
+		`"translatedExamples":["Наш дом старый.","Они купили дом."],"partOfSpeech":"Noun"}`
+	// the examples are missing
+	invalidReply = `{"originalLexicalItem":"house","originalMeaning":"a building for people to live in",` +
+		`"translatedFrom":"english","translatedTo":"russian","translatedLexicalItem":"дом","translatedMeaning":"здание для жилья"}`
+)
+
+func TestLLMTranslatorTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []string
+		calls    int
+		wantCode domain.TranslationErrorCode
+	}{
+		{name: "valid reply", replies: []string{validReply}, calls: 1},
+		{name: "code-fenced reply", replies: []string{"```json\n" + validReply + "\n```"}, calls: 1},
+		{name: "reply with commentary", replies: []string{"Here you go: " + validReply + " Enjoy!"}, calls: 1},
+		{name: "malformed reply repaired", replies: []string{"{not json", validReply}, calls: 2},
+		{name: "invalid reply repaired", replies: []string{invalidReply, validReply}, calls: 2},
+		{name: "invalid after repair", replies: []string{invalidReply, invalidReply}, calls: 2, wantCode: domain.TranslationErrorInvalidTranslation},
+		{name: "malformed after repair", replies: []string{"{not json", "still not json"}, calls: 2, wantCode: domain.TranslationErrorMalformedResponse},
+		{name: "provider unavailable", calls: 1, wantCode: domain.TranslationErrorProviderUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{replies: tt.replies}
+			translation, err := NewLLMTranslator(llm).Translate(context.Background(), "house", "english", "russian")
+			if len(llm.requests) != tt.calls {
+				t.Errorf("LLM called %d times, want %d", len(llm.requests), tt.calls)
+			}
+			if tt.wantCode != "" {
+				var translationErr *domain.TranslationError
+				if !errors.As(err, &translationErr) || translationErr.Code != tt.wantCode {
+					t.Fatalf("Translate() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+			if translation.TranslatedLexicalItem != "дом" || len(translation.OriginalExamples) != 2 {
+				t.Errorf("Translate() = %+v", translation)
+			}
+			if translation.TranslatedFrom != "english" || translation.TranslatedTo != "russian" {
+				t.Errorf("Translate() languages = %q -> %q, want english -> russian", translation.TranslatedFrom, translation.TranslatedTo)
+			}
+			if translation.PartOfSpeech != "noun" {
+				t.Errorf("Translate() partOfSpeech = %q, want noun", translation.PartOfSpeech)
+			}
+			if tt.calls == 2 {
+				repair := llm.requests[1].Messages
+				if len(repair) != 3 || repair[1].Role != domain.LLMRoleAssistant || repair[1].Content != tt.replies[0] || repair[2].Role != domain.LLMRoleUser {
+					t.Errorf("repair request messages = %+v", repair)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanJSON(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"a":1}`, `{"a":1}`},
+		{"```json\n{\"a\":1}\n```", `{"a":1}`},
+		{"```\n{\"a\":1}\n```", `{"a":1}`},
+		{"```{\"a\":1}```", `{"a":1}`},
+		{`Sure! {"a":{"b":2}} Hope it helps`, `{"a":{"b":2}}`},
+		{"no json", "no json"},
+	}
+	for _, tt := range tests {
+		if got := cleanJSON(tt.content); got != tt.want {
+			t.Errorf("cleanJSON(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}